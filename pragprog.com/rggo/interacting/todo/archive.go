@@ -0,0 +1,107 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ArchiveFile returns the name of the archive kept next to the list
+// file. The archive is always stored as JSON
+func ArchiveFile(filename string) string {
+	return filename + ".archive"
+}
+
+// Archive removes the items completed before the cutoff time from the
+// list and appends them to archive, preserving their completion time.
+// It returns how many items were archived
+func (l *List) Archive(archive *List, cutoff time.Time) int {
+	kept := List{}
+	n := 0
+
+	for _, t := range *l {
+		if t.Done && t.CompletedAt.Before(cutoff) {
+			*archive = append(*archive, t)
+			n++
+			continue
+		}
+		kept = append(kept, t)
+	}
+
+	*l = kept
+	return n
+}
+
+// Restore moves item i of the archive back to the end of the list
+func (l *List) Restore(archive *List, i int) error {
+	a := *archive
+	if i <= 0 || i > len(a) {
+		return fmt.Errorf("Archived item %d does not exist", i)
+	}
+
+	*l = append(*l, a[i-1])
+	*archive = append(a[:i-1], a[i:]...)
+
+	return nil
+}
+
+// Archive moves the items completed before the cutoff time from the
+// list to the archive, like List.Archive, and records the operation
+func (j *Journal) Archive(l *List, archive *List, cutoff time.Time) int {
+	// Removals are recorded from the last item, so each index
+	// is still valid when they're replayed in order
+	var changes []Change
+	for k := len(*l) - 1; k >= 0; k-- {
+		if t := (*l)[k]; t.Done && t.CompletedAt.Before(cutoff) {
+			changes = append(changes, Change{Index: k, Before: copyItem(t)})
+		}
+	}
+
+	n := l.Archive(archive, cutoff)
+	if n > 0 {
+		j.record(OpArchive, 0, changes...)
+	}
+
+	return n
+}
+
+// Restore moves item i of the archive back to the end of the
+// list and records the operation
+func (j *Journal) Restore(l *List, archive *List, i int) error {
+	if err := l.Restore(archive, i); err != nil {
+		return err
+	}
+
+	k := len(*l) - 1
+	j.record(OpRestore, 0, Change{Index: k, After: copyItem((*l)[k])})
+	return nil
+}
+
+// SyncArchive updates the archive once the archive or restore entry
+// e was undone, or redone when redo is set. Items back in the list are
+// removed from the archive, matched by ID, and items taken out of the
+// list are appended to it. It reports whether items were appended
+func (e Entry) SyncArchive(archive *List, redo bool) bool {
+	if e.Op != OpArchive && e.Op != OpRestore {
+		return false
+	}
+
+	appended := false
+	for k := len(e.Changes) - 1; k >= 0; k-- {
+		c := e.Changes[k]
+		from, to := c.After, c.Before
+		if redo {
+			from, to = c.Before, c.After
+		}
+
+		switch {
+		case to == nil:
+			*archive = append(*archive, from.clone())
+			appended = true
+		case from == nil:
+			*archive = slices.DeleteFunc(*archive, func(t item) bool { return t.ID == to.ID })
+		}
+	}
+
+	return appended
+}
@@ -0,0 +1,105 @@
+package todo_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestArchiveRestore tests moving completed items to an archive and back
+func TestArchiveRestore(t *testing.T) {
+	l := todo.List{}
+	l.Add("Task 1")
+	l.Add("Task 2")
+	l.Add("Task 3")
+	l.Complete(1)
+	l.Complete(3)
+
+	archive := todo.List{}
+
+	// Nothing was completed before yesterday
+	if n := l.Archive(&archive, time.Now().AddDate(0, 0, -1)); n != 0 {
+		t.Errorf("Expected %d archived items, got %d instead.", 0, n)
+	}
+
+	completedAt := l[0].CompletedAt
+	if n := l.Archive(&archive, time.Now()); n != 2 {
+		t.Fatalf("Expected %d archived items, got %d instead.", 2, n)
+	}
+
+	if len(l) != 1 || l[0].Task != "Task 2" {
+		t.Errorf("Expected only the pending task left, got %v instead.", l)
+	}
+
+	if len(archive) != 2 || !archive[0].CompletedAt.Equal(completedAt) {
+		t.Errorf("Expected archived items to keep their completion time, got %v instead.", archive)
+	}
+
+	if err := l.Restore(&archive, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 2 || l[1].Task != "Task 3" || !l[1].Done {
+		t.Errorf("Expected restored completed task, got %v instead.", l)
+	}
+
+	if len(archive) != 1 {
+		t.Errorf("Expected %d archived items, got %d instead.", 1, len(archive))
+	}
+
+	if err := l.Restore(&archive, 5); err == nil {
+		t.Errorf("Expected error restoring a missing item")
+	}
+}
+
+// TestArchiveJournal tests undoing archiving and restoring items
+func TestArchiveJournal(t *testing.T) {
+	l := todo.List{}
+	j := todo.NewJournal(filepath.Join(t.TempDir(), "todo.json"))
+	for _, task := range []string{"Task 1", "Task 2", "Task 3"} {
+		j.Add(&l, task)
+	}
+	j.Complete(&l, 1)
+	j.Complete(&l, 3)
+
+	archive := todo.List{}
+	if n := j.Archive(&l, &archive, time.Now()); n != 2 {
+		t.Fatalf("Expected %d archived items, got %d instead.", 2, n)
+	}
+
+	e, err := j.Undo(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.SyncArchive(&archive, false) {
+		t.Error("Expected no items appended to the archive")
+	}
+	if len(l) != 3 || l[0].Task != "Task 1" || l[2].Task != "Task 3" || len(archive) != 0 {
+		t.Fatalf("Expected archiving undone, got %v and %v instead.", l, archive)
+	}
+
+	e, err = j.Redo(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.SyncArchive(&archive, true) {
+		t.Error("Expected items appended to the archive")
+	}
+	if len(l) != 1 || len(archive) != 2 || archive[0].Task != "Task 1" {
+		t.Fatalf("Expected archiving redone, got %v and %v instead.", l, archive)
+	}
+
+	// A restored item goes back to the archive when it's undone
+	if err := j.Restore(&l, &archive, 2); err != nil {
+		t.Fatal(err)
+	}
+	if e, err = j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+	e.SyncArchive(&archive, false)
+	if len(l) != 1 || len(archive) != 2 || archive[1].Task != "Task 3" {
+		t.Errorf("Expected restore undone, got %v and %v instead.", l, archive)
+	}
+}
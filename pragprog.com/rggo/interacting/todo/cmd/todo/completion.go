@@ -0,0 +1,354 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// Kinds of flag values completed by the shell completion scripts.
+// Tasks, tags and lists are asked to the program with '-candidates'.
+// Top tasks are the pending tasks of the list itself, without subtasks
+const (
+	compTasks   = "tasks"
+	compTop     = "top-tasks"
+	compTags    = "tags"
+	compLists   = "lists"
+	compFiles   = "files"
+	compChoices = "choices"
+)
+
+// completion describes how the value of a flag is completed
+type completion struct {
+	kind    string
+	choices []string
+}
+
+// flagCompletions maps the flags taking a value that can be
+// completed. The values of the other flags are typed freely
+var flagCompletions = map[string]completion{
+	"complete":   {kind: compTasks},
+	"del":        {kind: compTasks},
+	"edit":       {kind: compTasks},
+	"sub":        {kind: compTasks},
+	"start":      {kind: compTasks},
+	"stop":       {kind: compTasks},
+	"dep":        {kind: compTasks},
+	"undep":      {kind: compTasks},
+	"on":         {kind: compTasks},
+	"move":       {kind: compTop},
+	"l":          {kind: compLists},
+	"to":         {kind: compLists},
+	"template":   {kind: compFiles},
+	"format":     {kind: compChoices, choices: []string{"text", "table", "json", "csv", "dot"}},
+	"export":     {kind: compChoices, choices: []string{"txt", "md", "ics"}},
+	"import":     {kind: compChoices, choices: []string{"txt", "md", "ics"}},
+	"store":      {kind: compChoices, choices: []string{"json", "txt", "log"}},
+	"sort":       {kind: compChoices, choices: []string{todo.SortCreated, todo.SortDue, todo.SortPriority, todo.SortTask}},
+	"completion": {kind: compChoices, choices: []string{"bash", "zsh", "fish"}},
+	"candidates": {kind: compChoices, choices: []string{compTasks, compTop, compTags, compLists}},
+}
+
+// compFlag is a flag as seen by the completion scripts
+type compFlag struct {
+	name  string
+	usage string
+	value bool
+	comp  completion
+}
+
+// completionFlags function returns the flags of fs sorted by name
+func completionFlags(fs *flag.FlagSet) []compFlag {
+	var flags []compFlag
+
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, compFlag{
+			name:  f.Name,
+			usage: f.Usage,
+			value: !ok || !b.IsBoolFlag(),
+			comp:  flagCompletions[f.Name],
+		})
+	})
+	sort.Slice(flags, func(a, b int) bool { return flags[a].name < flags[b].name })
+
+	return flags
+}
+
+// completionScript function writes the completion script of the
+// given shell for the flags of fs
+func completionScript(w io.Writer, shell string, fs *flag.FlagSet) error {
+	prog := strings.TrimSuffix(filepath.Base(fs.Name()), ".exe")
+	flags := completionFlags(fs)
+
+	// Shell function names can't contain every character of a file name
+	fn := "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(prog, "_")
+
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion(prog, fn, flags)
+	case "zsh":
+		script = zshCompletion(prog, fn, flags)
+	case "fish":
+		script = fishCompletion(prog, fn, flags)
+	default:
+		return fmt.Errorf("Unknown shell %q: use bash, zsh or fish", shell)
+	}
+
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// bashCompletion function returns the bash completion script. Bash
+// can't show descriptions, so tasks are listed with their text
+// until a single one matches, which completes its number only
+func bashCompletion(prog, fn string, flags []compFlag) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# bash completion for %s, generated by '%s -completion bash'\n", prog, prog)
+	fmt.Fprintf(&b, "# Load it with: source <(%s -completion bash)\n\n", prog)
+
+	fmt.Fprintf(&b, `%[1]s_candidates() {
+    local i list=()
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        if [[ ${COMP_WORDS[i]} == -l || ${COMP_WORDS[i]} == --l ]]; then
+            list=(-l "${COMP_WORDS[i+1]}")
+        fi
+    done
+    "${COMP_WORDS[0]}" "${list[@]}" -candidates "$1" 2>/dev/null
+}
+
+%[1]s_tasks() {
+    local IFS=$'\n' line
+    for line in $(%[1]s_candidates "${1:-tasks}"); do
+        [[ ${line%%%%$'\t'*} == "$cur"* ]] && COMPREPLY+=("$line")
+    done
+    if ((${#COMPREPLY[@]} == 1)); then
+        COMPREPLY=("${COMPREPLY[0]%%%%$'\t'*}")
+    else
+        COMPREPLY=("${COMPREPLY[@]/$'\t'/  -- }")
+    fi
+}
+
+%[1]s() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    COMPREPLY=()
+
+    case "$prev" in
+`, fn)
+
+	var free []string
+	for _, f := range flags {
+		if !f.value {
+			continue
+		}
+
+		pattern := fmt.Sprintf("        -%s|--%s)", f.name, f.name)
+		switch f.comp.kind {
+		case compTasks:
+			fmt.Fprintf(&b, "%s\n            %s_tasks\n            return ;;\n", pattern, fn)
+		case compTop:
+			fmt.Fprintf(&b, "%s\n            %s_tasks %s\n            return ;;\n", pattern, fn, compTop)
+		case compLists:
+			fmt.Fprintf(&b, "%s\n            COMPREPLY=($(compgen -W \"$(%s_candidates lists)\" -- \"$cur\"))\n            return ;;\n", pattern, fn)
+		case compFiles:
+			fmt.Fprintf(&b, "%s\n            COMPREPLY=($(compgen -f -- \"$cur\"))\n            return ;;\n", pattern)
+		case compChoices:
+			fmt.Fprintf(&b, "%s\n            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n            return ;;\n", pattern, strings.Join(f.comp.choices, " "))
+		default:
+			free = append(free, "-"+f.name+"|--"+f.name)
+		}
+	}
+	fmt.Fprintf(&b, "        %s)\n            [[ $cur == [+@]* ]] && COMPREPLY=($(compgen -W \"$(%s_candidates tags)\" -- \"$cur\"))\n            return ;;\n",
+		strings.Join(free, "|"), fn)
+
+	names := make([]string, len(flags))
+	for k, f := range flags {
+		names[k] = "-" + f.name
+	}
+
+	fmt.Fprintf(&b, `    esac
+
+    case "$cur" in
+        -*)
+            COMPREPLY=($(compgen -W "%s" -- "$cur")) ;;
+        +* | @*)
+            COMPREPLY=($(compgen -W "$(%s_candidates tags)" -- "$cur")) ;;
+        *)
+            COMPREPLY=($(compgen -f -- "$cur")) ;;
+    esac
+}
+
+complete -F %s %s
+`, strings.Join(names, " "), fn, fn, prog)
+
+	return b.String()
+}
+
+// zshCompletion function returns the zsh completion script
+func zshCompletion(prog, fn string, flags []compFlag) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "#compdef %s\n", prog)
+	fmt.Fprintf(&b, "# zsh completion for %s, generated by '%s -completion zsh'\n", prog, prog)
+	fmt.Fprintf(&b, "# Save it as _%s in a directory of your $fpath\n\n", prog)
+
+	fmt.Fprintf(&b, `%[1]s_candidates() {
+    local i
+    local -a list
+    for ((i = 2; i < CURRENT; i++)); do
+        [[ $words[i] == (-l|--l) ]] && list=(-l "$words[i+1]")
+    done
+    "$words[1]" "${list[@]}" -candidates "$1" 2>/dev/null
+}
+
+%[1]s_tasks() {
+    local -a tasks
+    tasks=(${(f)"$(%[1]s_candidates "${1:-tasks}")"})
+    tasks=(${tasks/$'\t'/:})
+    _describe -t tasks task tasks
+}
+
+%[1]s_lists() {
+    local -a lists
+    lists=(${(f)"$(%[1]s_candidates lists)"})
+    _describe -t lists list lists
+}
+
+%[1]s_args() {
+    if [[ $PREFIX == [+@]* ]]; then
+        local -a tags
+        tags=(${(f)"$(%[1]s_candidates tags)"})
+        compadd -a tags
+    else
+        _files
+    fi
+}
+
+%[1]s() {
+    _arguments \
+`, fn)
+
+	for _, f := range flags {
+		desc := zshEscape(f.usage)
+		if !f.value {
+			fmt.Fprintf(&b, "        '-%s[%s]' \\\n", f.name, desc)
+			continue
+		}
+
+		action := " "
+		switch f.comp.kind {
+		case compTasks:
+			action = fn + "_tasks"
+		case compTop:
+			action = fn + "_tasks " + compTop
+		case compLists:
+			action = fn + "_lists"
+		case compFiles:
+			action = "_files"
+		case compChoices:
+			action = "(" + strings.Join(f.comp.choices, " ") + ")"
+		}
+		fmt.Fprintf(&b, "        '-%s[%s]:%s:%s' \\\n", f.name, desc, f.name, action)
+	}
+
+	fmt.Fprintf(&b, `        '*:argument:%[1]s_args'
+}
+
+%[1]s "$@"
+`, fn)
+
+	return b.String()
+}
+
+// zshEscape function escapes a flag description for _arguments
+func zshEscape(s string) string {
+	return strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(s)
+}
+
+// fishCompletion function returns the fish completion script
+func fishCompletion(prog, fn string, flags []compFlag) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# fish completion for %s, generated by '%s -completion fish'\n", prog, prog)
+	fmt.Fprintf(&b, "# Save it as ~/.config/fish/completions/%s.fish\n\n", prog)
+
+	fmt.Fprintf(&b, `function %[1]s_candidates
+    set -l tokens (commandline -opc)
+    set -l list
+    set -l i (contains -i -- -l $tokens)
+    if test -n "$i"; and set -q tokens[(math $i + 1)]
+        set list -l $tokens[(math $i + 1)]
+    end
+    $tokens[1] $list -candidates $argv 2>/dev/null
+end
+
+complete -c %[2]s -n 'string match -qr "^[+@]" -- (commandline -ct)' -a '(%[1]s_candidates tags)'
+`, fn, prog)
+
+	for _, f := range flags {
+		line := fmt.Sprintf("complete -c %s -o %s -d %s", prog, f.name, fishQuote(f.usage))
+
+		switch {
+		case !f.value:
+		case f.comp.kind == compTasks, f.comp.kind == compTop:
+			line += fmt.Sprintf(" -x -a '(%s_candidates %s)'", fn, f.comp.kind)
+		case f.comp.kind == compLists:
+			line += fmt.Sprintf(" -x -a '(%s_candidates lists)'", fn)
+		case f.comp.kind == compFiles:
+			line += " -r -F"
+		case f.comp.kind == compChoices:
+			line += " -x -a " + fishQuote(strings.Join(f.comp.choices, " "))
+		default:
+			line += " -x"
+		}
+		fmt.Fprintln(&b, line)
+	}
+
+	return b.String()
+}
+
+// fishQuote function quotes a string for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// printCandidates function prints the values offered by the shell
+// completion scripts: the pending tasks, with their address and text
+// separated by a tab, only the top ones for flags such as -move that
+// take items of the list itself, the tags used in the list or the
+// named lists
+func printCandidates(w io.Writer, kind string, l *todo.List) error {
+	switch kind {
+	case compTasks, compTop:
+		for _, r := range l.Rows(true) {
+			if kind == compTop && r.Depth > 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\n", r.Address, r.Task)
+		}
+	case compTags:
+		for _, tag := range l.Tags() {
+			fmt.Fprintln(w, tag)
+		}
+	case compLists:
+		names, err := todo.Lists()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Fprintln(w, name)
+		}
+	default:
+		return fmt.Errorf("Unknown candidates %q: use tasks, top-tasks, tags or lists", kind)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCompletionScript tests the completion scripts cover every flag
+func TestCompletionScript(t *testing.T) {
+	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
+	fs.Bool("list", false, "List all tasks")
+	fs.String("complete", "", "Items to be completed")
+	fs.Int("move", 0, "Item to be moved")
+	fs.String("format", "text", "Output format: text or json")
+	fs.String("due", "", "Due date for the task")
+
+	testCases := []struct {
+		shell    string
+		expected []string
+	}{
+		{"bash", []string{
+			"-complete|--complete)\n            _todo_tasks\n",
+			"-move|--move)\n            _todo_tasks top-tasks\n",
+			`compgen -W "text table json csv dot"`,
+			"-due|--due)",
+			`compgen -W "-complete -due -format -list -move"`,
+			"complete -F _todo todo",
+		}},
+		{"zsh", []string{
+			"#compdef todo",
+			"'-list[List all tasks]'",
+			"'-complete[Items to be completed]:complete:_todo_tasks'",
+			"'-move[Item to be moved]:move:_todo_tasks top-tasks'",
+			"'-format[Output format\\: text or json]:format:(text table json csv dot)'",
+			"'-due[Due date for the task]:due: '",
+		}},
+		{"fish", []string{
+			"complete -c todo -o list -d 'List all tasks'\n",
+			"complete -c todo -o complete -d 'Items to be completed' -x -a '(_todo_candidates tasks)'",
+			"complete -c todo -o move -d 'Item to be moved' -x -a '(_todo_candidates top-tasks)'",
+			"complete -c todo -o due -d 'Due date for the task' -x\n",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.shell, func(t *testing.T) {
+			var b strings.Builder
+			if err := completionScript(&b, tc.shell, fs); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tc.expected {
+				if !strings.Contains(b.String(), s) {
+					t.Errorf("Expected %q in script, got %q instead.", s, b.String())
+				}
+			}
+
+			// Check the syntax when the shell is installed
+			sh, err := exec.LookPath(tc.shell)
+			if err != nil {
+				return
+			}
+
+			cmd := exec.Command(sh, "-n")
+			cmd.Stdin = strings.NewReader(b.String())
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("Invalid %s script: %s: %s", tc.shell, err, out)
+			}
+		})
+	}
+
+	var b strings.Builder
+	if err := completionScript(&b, "powershell", fs); err == nil {
+		t.Error("Expected error for an unknown shell")
+	}
+}
+
+// TestCompletionProgramName tests scripts complete the program
+// they were generated by
+func TestCompletionProgramName(t *testing.T) {
+	fs := flag.NewFlagSet(filepath.Join("bin", "my-todo.exe"), flag.ContinueOnError)
+
+	var b strings.Builder
+	if err := completionScript(&b, "bash", fs); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(b.String(), "complete -F _my_todo my-todo\n") {
+		t.Errorf("Unexpected script %q", b.String())
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// setting is an effective configuration setting and where it comes from
+type setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// loadConfig function reads the user configuration and the
+// project configuration nearest to the current directory
+func loadConfig() (todo.Config, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return todo.Config{}, err
+	}
+
+	return todo.LoadConfig(dir)
+}
+
+// configSource function describes the configuration file a
+// setting was read from, or returns empty if it wasn't set
+func configSource(cfg todo.Config, name string) string {
+	src, ok := cfg.Sources[name]
+	switch {
+	case !ok:
+		return ""
+	case cfg.FromProject(name):
+		return "project " + src
+	}
+
+	return "user " + src
+}
+
+// display holds the settings of how lists are shown
+type display struct {
+	timeLayout string
+	dateLayout string
+	sort       string
+	color      bool
+}
+
+// applyConfig function resolves the configuration settings, except
+// those overridden by the flags given, and returns the effective
+// settings and how lists are shown
+func applyConfig(cfg todo.Config, given map[string]bool, sortOrder string, color bool) ([]setting, display, error) {
+	source := func(name string) string {
+		if src := configSource(cfg, name); src != "" {
+			return src
+		}
+		return "default"
+	}
+
+	d := display{timeLayout: todo.DefaultTimeLayout, dateLayout: todo.DefaultDateLayout}
+	if cfg.TimeFormat != "" {
+		d.timeLayout = cfg.TimeFormat
+	}
+	if cfg.DateFormat != "" {
+		d.dateLayout = cfg.DateFormat
+	}
+
+	sortSource := source("sort")
+	switch {
+	case given["sort"]:
+		sortSource = "flag -sort"
+	case cfg.Sort != "":
+		sortOrder = cfg.Sort
+	}
+	if err := todo.CheckSortOrder(sortOrder); err != nil {
+		return nil, d, err
+	}
+	d.sort = sortOrder
+
+	// NO_COLOR is the usual way of turning color off for every program
+	colorSource := source("color")
+	switch {
+	case given["color"]:
+		colorSource = "flag -color"
+	case os.Getenv("NO_COLOR") != "":
+		color, colorSource = false, "env NO_COLOR"
+	case cfg.Color != nil:
+		color = *cfg.Color
+	}
+	d.color = color
+
+	settings := []setting{
+		{Name: "time_format", Value: d.timeLayout, Source: source("time_format")},
+		{Name: "date_format", Value: d.dateLayout, Source: source("date_format")},
+		{Name: "sort", Value: sortOrder, Source: sortSource},
+		{Name: "color", Value: strconv.FormatBool(color), Source: colorSource},
+	}
+
+	for _, name := range cfg.AliasNames() {
+		settings = append(settings, setting{
+			Name:   "aliases." + name,
+			Value:  cfg.Aliases[name],
+			Source: source("aliases." + name),
+		})
+	}
+
+	return settings, d, nil
+}
+
+// printConfig function prints the effective settings as text or JSON
+func printConfig(w io.Writer, settings []setting, format string) error {
+	switch format {
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, s := range settings {
+			fmt.Fprintf(tw, "%s\t%s\t(%s)\n", s.Name, s.Value, s.Source)
+		}
+		return tw.Flush()
+
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(settings)
+	}
+
+	return fmt.Errorf("Unknown configuration format %q", format)
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// Interactive mode states
+const (
+	modeNormal = iota
+	modeFilter
+	modeAdd
+	modeEdit
+	modeDelete
+)
+
+// errChanged is reported when another process changed the list
+// after it was displayed
+var errChanged = errors.New("List changed by another process, reloaded")
+
+// tui is the interactive terminal mode. It keeps the list on screen
+// and applies each action with a full lock, load, modify and save
+// cycle, so other processes can use the list during a session
+type tui struct {
+	filename  string
+	storeKind string
+	l         *todo.List
+
+	rows   []int // numbers of the items shown after filtering
+	cursor int   // selected position in rows
+	offset int   // first position in rows shown on screen
+
+	mode   int
+	filter string
+	input  string
+	msg    string
+}
+
+// runInteractive function runs the interactive mode
+// on the terminal connected to STDIN and STDOUT
+func runInteractive(filename, storeKind string) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("Interactive mode requires a terminal")
+	}
+
+	u, err := newTUI(filename, storeKind)
+	if err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	r := bufio.NewReader(os.Stdin)
+	for {
+		_, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			height = 24
+		}
+		u.render(os.Stdout, height)
+
+		k, err := readKey(r)
+		if err != nil {
+			return err
+		}
+
+		if u.handle(k) {
+			// Clear the screen on the way out
+			fmt.Fprint(os.Stdout, "\x1b[H\x1b[2J")
+			return nil
+		}
+	}
+}
+
+// newTUI returns the interactive mode for the list file
+func newTUI(filename, storeKind string) (*tui, error) {
+	u := &tui{filename: filename, storeKind: storeKind}
+
+	// Loading with a no-op update also validates the store
+	if err := u.update(0, func(*todo.List, *todo.Journal) error { return nil }); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// readKey reads a key press from the terminal, decoding the
+// escape sequences of the arrow keys
+func readKey(r *bufio.Reader) (string, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+
+	switch c {
+	case '\r', '\n':
+		return "enter", nil
+	case 127, '\b':
+		return "backspace", nil
+	case 3:
+		return "ctrl-c", nil
+	case 27:
+		// A lone escape is not followed by the rest of a sequence
+		if r.Buffered() < 2 {
+			return "esc", nil
+		}
+
+		seq := make([]byte, 2)
+		if _, err := io.ReadFull(r, seq); err != nil {
+			return "", err
+		}
+
+		switch string(seq) {
+		case "[A":
+			return "up", nil
+		case "[B":
+			return "down", nil
+		case "[H":
+			return "home", nil
+		case "[F":
+			return "end", nil
+		}
+		return "", nil
+	}
+
+	return string(c), nil
+}
+
+// handle applies a key press and reports whether to quit
+func (u *tui) handle(k string) bool {
+	u.msg = ""
+
+	switch u.mode {
+	case modeFilter:
+		switch k {
+		case "enter":
+			u.mode = modeNormal
+		case "esc":
+			u.filter = ""
+			u.mode = modeNormal
+		default:
+			u.filter = typed(u.filter, k)
+		}
+		u.refresh()
+
+	case modeAdd, modeEdit:
+		switch k {
+		case "enter":
+			u.commit()
+			u.mode = modeNormal
+		case "esc":
+			u.mode = modeNormal
+		default:
+			u.input = typed(u.input, k)
+		}
+
+	case modeDelete:
+		if k == "y" {
+			n := u.selected()
+			u.apply(n, func(l *todo.List, j *todo.Journal) error { return j.Delete(l, n) })
+		}
+		u.mode = modeNormal
+
+	default:
+		switch k {
+		case "q", "ctrl-c":
+			return true
+		case "up", "k":
+			u.move(-1)
+		case "down", "j":
+			u.move(1)
+		case "home", "g":
+			u.move(-len(u.rows))
+		case "end", "G":
+			u.move(len(u.rows))
+		case " ", "x":
+			u.toggle()
+		case "a":
+			u.mode, u.input = modeAdd, ""
+		case "e":
+			if n := u.selected(); n > 0 {
+				u.mode, u.input = modeEdit, (*u.l)[n-1].Task
+			}
+		case "d":
+			if u.selected() > 0 {
+				u.mode = modeDelete
+			}
+		case "/":
+			u.mode = modeFilter
+		case "u":
+			u.undo()
+		}
+	}
+
+	return false
+}
+
+// typed returns s after applying the key typed in a text prompt
+func typed(s, k string) string {
+	switch {
+	case k == "backspace":
+		if r := []rune(s); len(r) > 0 {
+			return string(r[:len(r)-1])
+		}
+		return s
+	case len([]rune(k)) == 1:
+		return s + k
+	}
+
+	return s
+}
+
+// move moves the cursor by delta rows
+func (u *tui) move(delta int) {
+	u.cursor = max(0, min(u.cursor+delta, len(u.rows)-1))
+}
+
+// selected returns the number of the item under the
+// cursor, or 0 when no item is shown
+func (u *tui) selected() int {
+	if len(u.rows) == 0 {
+		return 0
+	}
+
+	return u.rows[u.cursor]
+}
+
+// toggle completes the selected item or reopens it if it's done
+func (u *tui) toggle() {
+	n := u.selected()
+	if n == 0 {
+		return
+	}
+
+	u.apply(n, func(l *todo.List, j *todo.Journal) error {
+		if (*l)[n-1].Done {
+			return j.Reopen(l, n)
+		}
+		return j.Complete(l, n)
+	})
+}
+
+// commit adds or edits the item typed in the prompt
+func (u *tui) commit() {
+	task := strings.TrimSpace(u.input)
+	if task == "" {
+		u.msg = "Task cannot be blank"
+		return
+	}
+
+	if u.mode == modeAdd {
+		u.apply(0, func(l *todo.List, j *todo.Journal) error {
+			j.Add(l, task)
+			return nil
+		})
+		return
+	}
+
+	n := u.selected()
+	u.apply(n, func(l *todo.List, j *todo.Journal) error { return j.Edit(l, n, task) })
+}
+
+// apply runs an update and shows any error on the status line
+func (u *tui) apply(n int, fn func(*todo.List, *todo.Journal) error) {
+	if err := u.update(n, fn); err != nil {
+		u.msg = err.Error()
+	}
+}
+
+// undo undoes the last change, moving tasks between the list
+// and the archive when it archived or restored them
+func (u *tui) undo() {
+	err := u.change(0, func(s todo.Store, l *todo.List, j *todo.Journal) error {
+		e, err := j.Undo(l)
+		if err != nil {
+			return err
+		}
+
+		return saveUndone(s, u.filename, l, j, e, false)
+	})
+	if err != nil {
+		u.msg = err.Error()
+	}
+}
+
+// update reloads the list and applies fn while holding the lock,
+// then saves the list and its journal. When n is not 0, fn is only
+// applied if item n is still the one on screen
+func (u *tui) update(n int, fn func(*todo.List, *todo.Journal) error) error {
+	return u.change(n, func(s todo.Store, l *todo.List, j *todo.Journal) error {
+		if err := fn(l, j); err != nil {
+			return err
+		}
+
+		if err := s.Save(l); err != nil {
+			return err
+		}
+
+		return j.Commit()
+	})
+}
+
+// change reloads the list and runs fn, which saves it, like update
+func (u *tui) change(n int, fn func(todo.Store, *todo.List, *todo.Journal) error) error {
+	s, err := todo.NewStore(u.filename, u.storeKind)
+	if err != nil {
+		return err
+	}
+
+	lock, err := todo.Lock(u.filename)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	l := &todo.List{}
+	if err := s.Load(l); err != nil {
+		return err
+	}
+
+	// Always show the latest list, even if fn fails
+	shown := u.l
+	u.l = l
+	defer u.refresh()
+
+	if n > 0 && (n > len(*l) || (*shown)[n-1].Task != (*l)[n-1].Task) {
+		return errChanged
+	}
+
+	return fn(s, l, newJournal(s, u.filename))
+}
+
+// refresh recomputes the rows shown after filtering
+func (u *tui) refresh() {
+	u.rows = u.rows[:0]
+
+	if u.filter != "" {
+		// Only the items of the list itself are shown
+		for _, m := range u.l.Search(u.filter, true) {
+			if len(m.Path) == 1 {
+				u.rows = append(u.rows, m.Path[0])
+			}
+		}
+	} else {
+		for k := range *u.l {
+			u.rows = append(u.rows, k+1)
+		}
+	}
+
+	u.move(0)
+}
+
+// render draws the screen for a terminal with the given height
+func (u *tui) render(w io.Writer, height int) {
+	var b strings.Builder
+
+	// Clear the screen and move to the top
+	b.WriteString("\x1b[H\x1b[2J")
+
+	pending := 0
+	for _, t := range *u.l {
+		if !t.Done {
+			pending++
+		}
+	}
+	fmt.Fprintf(&b, "%s: %d tasks, %d pending", u.filename, len(*u.l), pending)
+	if u.filter != "" {
+		fmt.Fprintf(&b, ", filter %q", u.filter)
+	}
+	b.WriteString("\r\n\r\n")
+
+	// Keep the cursor within the visible rows
+	visible := max(1, height-4)
+	if u.cursor < u.offset {
+		u.offset = u.cursor
+	}
+	if u.cursor >= u.offset+visible {
+		u.offset = u.cursor - visible + 1
+	}
+
+	for k := u.offset; k < len(u.rows) && k < u.offset+visible; k++ {
+		n := u.rows[k]
+		t := (*u.l)[n-1]
+
+		cursor := "  "
+		if k == u.cursor {
+			cursor = "> "
+		}
+
+		mark := "[ ]"
+		if t.Done {
+			mark = "[x]"
+		}
+
+		fmt.Fprintf(&b, "%s%s %d: %s\r\n", cursor, mark, n, t.Task)
+	}
+
+	b.WriteString("\r\n")
+	switch {
+	case u.mode == modeAdd:
+		fmt.Fprintf(&b, "Add: %s", u.input)
+	case u.mode == modeEdit:
+		fmt.Fprintf(&b, "Edit: %s", u.input)
+	case u.mode == modeFilter:
+		fmt.Fprintf(&b, "Filter: %s", u.filter)
+	case u.mode == modeDelete:
+		fmt.Fprintf(&b, "Delete item %d? (y/n)", u.selected())
+	case u.msg != "":
+		b.WriteString(u.msg)
+	default:
+		b.WriteString("j/k move  space toggle  a add  e edit  d delete  / filter  u undo  q quit")
+	}
+
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// keys feeds a sequence of key presses to the interactive mode,
+// typing each character of text entries
+func keys(u *tui, presses ...string) {
+	for _, p := range presses {
+		switch p {
+		case "up", "down", "enter", "esc", "backspace":
+			u.handle(p)
+		default:
+			for _, c := range p {
+				u.handle(string(c))
+			}
+		}
+	}
+}
+
+// TestInteractive tests editing the list with key presses
+func TestInteractive(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	u, err := newTUI(filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys(u, "a", "first task", "enter", "a", "second tsk", "enter")
+	keys(u, "down", "e", "backspace", "backspace", "ask", "enter")
+	keys(u, "up", " ")
+
+	l := todo.List{}
+	if err := l.Get(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "X 1: first task\n  2: second task\n"
+	if l.String() != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, l.String())
+	}
+
+	// Filtering as you type narrows the rows to the matches
+	keys(u, "/", "seco")
+	if len(u.rows) != 1 || u.selected() != 2 {
+		t.Errorf("Expected only item 2 shown, got %v instead.", u.rows)
+	}
+
+	keys(u, "enter", "d", "y", "esc")
+	if err := l.Get(filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 {
+		t.Errorf("Expected %d items, got %d instead.", 1, len(l))
+	}
+
+	var out bytes.Buffer
+	keys(u, "/", "esc")
+	u.render(&out, 10)
+	if !strings.Contains(out.String(), "> [x] 1: first task") {
+		t.Errorf("Expected selected completed task on screen, got %q instead.", out.String())
+	}
+
+	if !u.handle("q") {
+		t.Errorf("Expected q to quit")
+	}
+}
+
+// TestInteractiveChanged tests that changes made by other
+// processes are detected before applying an action
+func TestInteractiveChanged(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	u, err := newTUI(filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys(u, "a", "first task", "enter")
+
+	l := todo.List{}
+	l.Add("replaced task")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	keys(u, " ")
+	if u.msg != errChanged.Error() {
+		t.Errorf("Expected %q, got %q instead.", errChanged, u.msg)
+	}
+
+	if (*u.l)[0].Task != "replaced task" || (*u.l)[0].Done {
+		t.Errorf("Expected the reloaded list, got %v instead.", *u.l)
+	}
+}
+
+// TestInteractiveUndoArchive tests undoing archiving tasks moves
+// them out of the archive
+func TestInteractiveUndoArchive(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	l := todo.List{}
+	j := todo.NewJournal(filename)
+	j.Add(&l, "first task")
+	j.Complete(&l, 1)
+
+	a := todo.List{}
+	j.Archive(&l, &a, time.Now())
+	if err := a.Save(todo.ArchiveFile(filename)); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := newTUI(filename, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys(u, "u")
+	if u.msg != "" {
+		t.Fatal(u.msg)
+	}
+
+	if err := a.Get(todo.ArchiveFile(filename)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*u.l) != 1 || len(a) != 0 {
+		t.Errorf("Expected the task back in the list only, got %v and %v instead.", *u.l, a)
+	}
+}
+
+// TestReadKey tests decoding key presses
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("\x1b[A\x1b[Bj\r\x7f"))
+
+	for _, exp := range []string{"up", "down", "j", "enter", "backspace"} {
+		k, err := readKey(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k != exp {
+			t.Errorf("Expected %q, got %q instead.", exp, k)
+		}
+	}
+}
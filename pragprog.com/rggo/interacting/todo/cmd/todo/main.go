@@ -2,18 +2,30 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"golang.org/x/term"
 	"pragprog.com/rggo/interacting/todo"
 )
 
-// Hardcoding the file name
+// Hardcoding the file name. The nearest file with this name in the
+// current directory or its parents is used
 var todoFilename = ".todo.json"
 
+// archiveDays is the number of days after which completed tasks are
+// archived when the list is saved, set by TODO_ARCHIVE_DAYS. It's
+// negative when tasks are only archived with '-archive'
+var archiveDays = -1
+
 func main() {
 
 	flag.Usage = func() {
@@ -32,95 +44,690 @@ func main() {
 		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -complete 1")
 		fmt.Fprintln(flag.CommandLine.Output(), "  - To delete a task, use the '-del' flag followed by the task number.")
 		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -del 2")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To complete or delete several tasks at once, give '-complete' or '-del' a list of task numbers and ranges,")
+		fmt.Fprintln(flag.CommandLine.Output(), "    or a filter combining all, done, pending and older than N days (Nd) or weeks (Nw).")
+		fmt.Fprintln(flag.CommandLine.Output(), "    The tasks are chosen before any of them is changed, and '-undo' reverts them all.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -complete 1-4,7,9")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -del done older than 30d")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To change a task description, use the '-edit' flag followed by the task number and the new description.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -edit 1 Buy more groceries")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To add a subtask, use the '-sub' flag followed by the parent task number with '-add'.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Subtasks are numbered after their parent, like 3.2, in every flag taking a task number.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Completing the last pending subtask completes its parent, unless '-auto-parent=false' is given.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -add -sub 3 Write release notes")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -complete 3.1")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To schedule a task, use the '-due' and '-recur' flags with '-add' or '-edit'.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Recurrence rules: daily, weekly, weekly=mon,thu, monthly, monthly=15, every=3d (days after completion).")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Completing a recurring task adds its next occurrence.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -add -due 2024-05-01 -recur monthly=1 Rotate certificates")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To be reminded of a task, use the '-remind-at' flag with '-add' or '-edit', and run './todo -remind'")
+		fmt.Fprintln(flag.CommandLine.Output(), "    from cron or your shell startup. Each reminder is shown once, when its time has passed.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -add -due 2024-05-01 -remind-at 09:00 Renew passport")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see how many tasks are pending, due today and overdue, use the '-status' flag. It exits")
+		fmt.Fprintln(flag.CommandLine.Output(), "    with status 2 when some tasks are overdue, and is fast enough for shell prompts.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: PS1='$(./todo -status) \\$ '")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To attach notes to a task, use the '-notes' flag with '-add' or '-edit'.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To find tasks, use the '-search' flag followed by the query. Add '-done' to include completed tasks.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -search \"rotate certs\"")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To revert the last change, use the '-undo' flag. Use '-redo' to apply it again.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see every change made to the list, use the '-history' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To share the list with todo.txt apps, use the '-export' and '-import' flags.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -export txt > todo.txt")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -import txt todo.txt")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To paste the list into documents as a Markdown checklist, use '-export md'. Add '-group' to group it by project.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -import md README.md")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see tasks with a due date in calendar apps, use '-export ics'. Add '-events' to also show them as all-day events.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Calendar apps can subscribe to the generated file. Use '-import ics' to import the tasks of an iCalendar file.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -export ics > ~/Public/todo.ics")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To move completed tasks to the archive, use the '-archive' flag. Add '-days' to keep recently completed ones.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -archive -days 30")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To list archived tasks, use the '-archived' flag. Combine it with '-search' to search the archive.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To bring an archived task back, use the '-restore' flag followed by the archived task number.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see statistics for the last two weeks, including archived tasks, use the '-stats' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -stats -format json")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To track the time worked on a task, use the '-start' and '-stop' flags followed by the task number.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Listings show the tasks being worked on. Completing a task stops its session.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -start 2")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see the time worked per task, tag and day, use the '-report' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -report -format json")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To make a task wait for others, use the '-dep' flag followed by the task number with '-on' and the tasks it depends on.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Listings show the pending tasks blocking it. Use '-undep' with '-on' to remove a dependency.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -dep 3 -on 1,2")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To list the pending tasks that are not blocked, use the '-ready' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see the dependencies between tasks, use the '-graph' flag. Add '-format dot' to draw it with Graphviz.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -graph -format dot | dot -Tsvg > todo.svg")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To encrypt the list, its archive and its journal with a passphrase, use the '-encrypt' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Encrypted lists stay encrypted until '-decrypt' is used. Only JSON lists can be encrypted.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To merge two copies of a list changed separately, use the '-merge' flag followed by the files of their")
+		fmt.Fprintln(flag.CommandLine.Output(), "    common ancestor, our copy and their copy. The result replaces our copy. Changes that can't be merged")
+		fmt.Fprintln(flag.CommandLine.Output(), "    keep our side, are reported, and make the command fail. It works as a git merge driver:")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: git config merge.todo.driver \"todo -merge %O %A %B\"")
+		fmt.Fprintln(flag.CommandLine.Output(), "             echo \".todo.json merge=todo\" >> .gitattributes")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To triage tasks interactively, use the '-i' flag. Move with the arrow keys or j/k, toggle")
+		fmt.Fprintln(flag.CommandLine.Output(), "    completion with space, add with 'a', edit with 'e', delete with 'd', filter with '/' and quit with 'q'.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To serve the list over an HTTP JSON API, use the '-serve' flag followed by the address.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -serve localhost:8080")
 		fmt.Fprintln(flag.CommandLine.Output(), "  - To view tasks with additional details (such as creation date), use the '-v' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To choose how tasks are listed, use the '-format' flag with text, table, json or csv.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -list -format table")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To list tasks with your own layout, use the '-template' flag followed by a Go text/template file.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    The template ranges over the rows, with fields such as .Address, .Task, .Done and .Due,")
+		fmt.Fprintln(flag.CommandLine.Output(), "    and the functions 'date' (e.g. {{date \"2006-01-02\" .Due}}) and 'indent' (e.g. {{indent .Depth}}).")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - The list used is the nearest .todo.json in the current directory or its parents,")
+		fmt.Fprintln(flag.CommandLine.Output(), "    unless a configuration file sets it, or your 'default' named list when there is none.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Use '-which' to see the file used.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To start a list for the current directory and those below it, use the '-init' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To use a named list kept in your data directory, use the '-l' flag followed by the list name.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Named lists are stored in $XDG_DATA_HOME/todo, or ~/.local/share/todo by default.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -l work -add Review pull requests")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see the named lists, use the '-lists' flag. Use '-all' to view the tasks of every list, with '-p' for pending ones.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To move a task to a named list, use the '-move' flag followed by the task number with '-to'.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -l personal -move 2 -to work")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To choose the order of listed tasks, use the '-sort' flag with created, due, priority or task.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Tasks keep their number, and subtasks stay below their parent.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To show overdue tasks in red, tasks due today in yellow and completed ones faint, use the '-color' flag.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To complete flags, task numbers, tags and list names in your shell, load the script printed by")
+		fmt.Fprintln(flag.CommandLine.Output(), "    the '-completion' flag for bash, zsh or fish.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: source <(./todo -completion bash)")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: ./todo -completion fish > ~/.config/fish/completions/todo.fish")
+		fmt.Fprintln(flag.CommandLine.Output(), "\nConfiguration:")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - Settings are read from the JSON file config.json in $XDG_CONFIG_HOME/todo, or ~/.config/todo by default,")
+		fmt.Fprintln(flag.CommandLine.Output(), "    and then from the nearest .todo.config.json in the current directory or its parents, which takes precedence.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Flags and environment variables take precedence over both. The settings are:")
+		fmt.Fprintln(flag.CommandLine.Output(), "      file         the list file, relative to the configuration file. The one of your user configuration")
+		fmt.Fprintln(flag.CommandLine.Output(), "                   replaces the 'default' named list, so lists found in the current directory still win")
+		fmt.Fprintln(flag.CommandLine.Output(), "      time_format  the Go layout of the times listed, 2006-01-02 15:04 by default")
+		fmt.Fprintln(flag.CommandLine.Output(), "      date_format  the Go layout of the due dates listed, 2006-01-02 by default")
+		fmt.Fprintln(flag.CommandLine.Output(), "      sort         the default order of the listed tasks")
+		fmt.Fprintln(flag.CommandLine.Output(), "      color        true to list tasks in color")
+		fmt.Fprintln(flag.CommandLine.Output(), "      aliases      names for common commands, expanded when given as the first argument")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Example: {\"sort\": \"due\", \"aliases\": {\"today\": \"-p -sort due\", \"done\": \"-complete\"}}")
+		fmt.Fprintln(flag.CommandLine.Output(), "             ./todo done 3")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - To see the effective settings and where each one comes from, use the '-config' flag.")
 		fmt.Fprintln(flag.CommandLine.Output(), "\nEnvironment Variables:")
 		fmt.Fprintln(flag.CommandLine.Output(), "  - You can set the TODO_FILENAME environment variable to specify a custom file name for the todo list.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    The '-l' flag takes precedence over it.")
+		fmt.Fprintln(flag.CommandLine.Output(), "    Files ending in .txt are kept in the todo.txt format and files ending in .log in an append-only log.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - You can set the NO_COLOR environment variable to list tasks without color, unless '-color' is given.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - You can set the TODO_PASSPHRASE environment variable to the passphrase of encrypted lists,")
+		fmt.Fprintln(flag.CommandLine.Output(), "    or TODO_PASSPHRASE_FILE to a file holding it. Otherwise the passphrase is asked on the terminal.")
+		fmt.Fprintln(flag.CommandLine.Output(), "  - You can set the TODO_ARCHIVE_DAYS environment variable to archive tasks completed more than that many days ago whenever the list is saved.")
 	}
 
 	// Parsing command line flags
 	add := flag.Bool("add", false, "Add task to the ToDo list")
 	list := flag.Bool("list", false, "List all tasks")
-	complete := flag.Int("complete", 0, "Item to be completed")
-	delete := flag.Int("del", 0, "Item to be deleted from list")
+	complete := flag.String("complete", "", "Items to be completed, such as 3, 3.2 for a subtask, 1-4,7 or a filter like done")
+	delete := flag.String("del", "", "Items to be deleted from list, such as 3, 3.2 for a subtask, 1-4,7 or a filter like done")
 	verbose := flag.Bool("v", false, "verbose view")
 	pending := flag.Bool("p", false, "List pending tasks")
+	edit := flag.String("edit", "", "Item to be edited, such as 3 or 3.2 for a subtask")
+	sub := flag.String("sub", "", "Item the task being added is a subtask of")
+	autoParent := flag.Bool("auto-parent", true, "Complete items when all their subtasks are completed")
+	undo := flag.Bool("undo", false, "Undo the last change")
+	redo := flag.Bool("redo", false, "Redo the last undone change")
+	history := flag.Bool("history", false, "Show the history of changes")
+	due := flag.String("due", "", "Due date (YYYY-MM-DD) for the task being added or edited")
+	recur := flag.String("recur", "", "Recurrence rule for the task being added or edited")
+	notes := flag.String("notes", "", "Notes for the task being added or edited")
+	remindAt := flag.String("remind-at", "", "Reminder time (YYYY-MM-DD HH:MM, or HH:MM on the due date) for the task being added or edited")
+	search := flag.String("search", "", "Search tasks matching the query")
+	searchDone := flag.Bool("done", false, "Include completed tasks in search results")
+	exportFmt := flag.String("export", "", "Export the list to STDOUT in the given format: txt, md or ics")
+	importFmt := flag.String("import", "", "Import tasks from files or STDIN in the given format: txt, md or ics")
+	group := flag.Bool("group", false, "Group the Markdown export by project")
+	events := flag.Bool("events", false, "Add calendar events on the due dates to the iCalendar export")
+	archive := flag.Bool("archive", false, "Move completed tasks to the archive")
+	days := flag.Int("days", 0, "Only archive tasks completed more than this many days ago")
+	archived := flag.Bool("archived", false, "List archived tasks, or search them with '-search'")
+	restore := flag.Int("restore", 0, "Archived item to be restored to the list")
+	stats := flag.Bool("stats", false, "Show statistics of created and completed tasks")
+	format := flag.String("format", "text", "Output format: text, table, json or csv (text or json for '-stats' and '-report')")
+	tmplFile := flag.String("template", "", "List tasks with the Go text/template in the given file")
+	interactive := flag.Bool("i", false, "Interactive mode to browse and edit the list")
+	serve := flag.String("serve", "", "Serve the list over an HTTP JSON API on the given address")
+	storeKind := flag.String("store", "", "Storage backend: json, txt or log (default by file extension)")
+	listName := flag.String("l", "", "Use the named list kept in the data directory")
+	lists := flag.Bool("lists", false, "Show the named lists")
+	all := flag.Bool("all", false, "List the tasks of every named list")
+	move := flag.Int("move", 0, "Item to be moved to the list given with '-to'")
+	to := flag.String("to", "", "Named list the item is moved to")
+	start := flag.String("start", "", "Item to start a work session on")
+	stop := flag.String("stop", "", "Item to stop the running work session of")
+	report := flag.Bool("report", false, "Show the time worked per task, tag and day")
+	encrypt := flag.Bool("encrypt", false, "Encrypt the list, its archive and its journal with a passphrase")
+	decrypt := flag.Bool("decrypt", false, "Decrypt the list, its archive and its journal")
+	merge := flag.Bool("merge", false, "Merge the list files given as arguments: base ours theirs")
+	dep := flag.String("dep", "", "Item to be made dependent on the items given with '-on'")
+	undep := flag.String("undep", "", "Item to remove the dependency on the item given with '-on' from")
+	on := flag.String("on", "", "Items the item given with '-dep' or '-undep' depends on, such as 1,2")
+	ready := flag.Bool("ready", false, "List pending tasks not blocked by other tasks")
+	graph := flag.Bool("graph", false, "Show the dependency graph, as text or with '-format dot' for Graphviz")
+	status := flag.Bool("status", false, "Show the number of pending, due today and overdue tasks, failing when some are overdue")
+	remind := flag.Bool("remind", false, "Show the reminders due since they were last shown")
+	completionShell := flag.String("completion", "", "Print the completion script of the given shell: bash, zsh or fish")
+	candidates := flag.String("candidates", "", "Print the tasks, tags or lists offered by the completion scripts")
+	sortOrder := flag.String("sort", todo.SortCreated, "Order of the listed tasks: created, due, priority or task")
+	color := flag.Bool("color", false, "Show overdue, due today and completed tasks in color")
+	showConfig := flag.Bool("config", false, "Show the effective configuration and where each setting comes from")
+	which := flag.Bool("which", false, "Show the list file used")
+	initList := flag.Bool("init", false, "Create an empty list in the current directory")
+
+	// Read the user and project configuration files, and expand an
+	// alias given as the first argument before parsing the flags
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	flag.CommandLine.Parse(cfg.Alias(os.Args[1:]))
+
+	// Flags given on the command line override the configuration
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	settings, disp, err := applyConfig(cfg, given, *sortOrder, *color)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *completionShell != "" {
+		if err := completionScript(os.Stdout, *completionShell, flag.CommandLine); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *initList {
+		if err := initTodo(todoFilename); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The list used is the named list given with '-l', the file in
+	// the TODO_FILENAME ENV VAR, the file of the project configuration,
+	// the nearest list, the file of the user configuration or the
+	// user's global list, in that order
+	var fileSource string
+	switch {
+	case *listName != "":
+		f, err := todo.ListFile(*listName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		todoFilename, fileSource = f, "flag -l"
+
+	case os.Getenv("TODO_FILENAME") != "":
+		todoFilename, fileSource = os.Getenv("TODO_FILENAME"), "env TODO_FILENAME"
+
+	case cfg.File != "" && cfg.FromProject("file"):
+		todoFilename, fileSource = cfg.File, configSource(cfg, "file")
+
+	default:
+		f, found, err := findTodo(todoFilename, cfg.File)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		todoFilename = f
+
+		switch {
+		case found:
+			fileSource = "nearest list"
+		case cfg.File != "":
+			fileSource = configSource(cfg, "file")
+		default:
+			fileSource = "default"
+		}
+	}
+
+	if *which {
+		fmt.Println(todoFilename)
+		return
+	}
+
+	if *showConfig {
+		settings = append([]setting{{Name: "file", Value: todoFilename, Source: fileSource}}, settings...)
+		if err := printConfig(os.Stdout, settings, *format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The passphrase is only asked for when an encrypted list is read
+	// or a list is encrypted, and confirmed in the latter case
+	todo.Passphrase = func() ([]byte, error) { return readPassphrase(*encrypt) }
+
+	// Completion runs on every key press, the status on every shell
+	// prompt and the reminders from cron, so they never ask for the
+	// passphrase
+	if *candidates != "" || *status || *remind {
+		todo.Passphrase = envPassphrase
+	}
+
+	// Completion only reads the list
+	if *candidates != "" {
+		if err := showCandidates(os.Stdout, *candidates, todoFilename, *storeKind); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The status and the reminders only read the list, without locking
+	// it or loading its journal, so shell prompts and cron jobs stay fast
+	if *status {
+		overdue, err := showStatus(os.Stdout, todoFilename, *storeKind, *format)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if overdue {
+			os.Exit(2)
+		}
+		return
+	}
+
+	if *remind {
+		if err := showReminders(os.Stdout, todoFilename, *storeKind, disp.dateLayout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *merge {
+		if err := mergeFiles(os.Stderr, flag.Args()...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Views across the named lists don't use the current list
+	if *lists || *all {
+		if err := showLists(os.Stdout, *all, *pending, disp); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The interactive mode and the server lock the list for each
+	// change instead of holding the lock until they exit
+	if *interactive {
+		if err := runInteractive(todoFilename, *storeKind); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	flag.Parse()
+	if *serve != "" {
+		fmt.Printf("Serving %s on http://%s/todo\n", todoFilename, *serve)
+		if err := http.ListenAndServe(*serve, newServer(todoFilename, *storeKind)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// Lock the list for the whole load, modify and save cycle so
+	// concurrent invocations don't lose each other's updates. The
+	// lock is also released by the OS if the program exits early
+	lock, err := todo.Lock(todoFilename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer lock.Unlock()
 
-	// Check if the user defined the ENV VAR for a custom file name
-	if os.Getenv("TODO_FILENAME") != "" {
-		todoFilename = os.Getenv("TODO_FILENAME")
+	// Choose how the list is stored
+	s, err := todo.NewStore(todoFilename, *storeKind)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	// Define an items list
 	l := &todo.List{}
 
-	// Use the Get method to read to do items from file
-	if err := l.Get(todoFilename); err != nil {
+	// Use the Load method to read to do items from the store
+	if err := s.Load(l); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
+	// Check if the user defined the ENV VAR to archive tasks automatically
+	// whenever the list is saved
+	if v := os.Getenv("TODO_ARCHIVE_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid TODO_ARCHIVE_DAYS %q\n", v)
+			os.Exit(1)
+		}
+		archiveDays = n
+	}
+
+	// Collect the optional attributes for new or edited tasks
+	opts, err := taskOptions(*due, *recur, *notes, *remindAt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Every change to the list is recorded in a journal next to it
+	j := newJournal(s, todoFilename)
+
 	// Decide what todo based on the number of arguments provided
 	switch {
 	// For no extra arguments, print the list
-	case *list:
-		// List current todo items
-		fmt.Print(l)
+	case *list, *verbose, *pending, *tmplFile != "":
+		// List current todo items, only the pending ones with '-p',
+		// in the chosen output format
+		if err := printList(os.Stdout, l.SortedRows(*pending, disp.sort), *verbose, disp, *format, *tmplFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 
-	case *verbose:
-		// List current todo items with created date/time
-		fmt.Print(l.Verbose())
+	case *complete != "":
+		// Complete the selected items, warning about the blocked ones
+		paths := selection(l, *complete, flag.Args()...)
+		warnBlocked(os.Stderr, l, paths)
+
+		if err := j.CompleteAll(l, paths, *autoParent); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 
-	case *pending:
-		// List all pending todo items
-		fmt.Print(l.Pend())
+		// Save the new list
+		save(s, l, j)
 
-	case *complete > 0:
-		// Complete the given item
-		if err := l.Complete(*complete); err != nil {
+	case *delete != "":
+		// Delete the selected items
+		if err := j.DeleteAll(l, selection(l, *delete, flag.Args()...)); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
 		// Save the new list
-		if err := l.Save(todoFilename); err != nil {
+		save(s, l, j)
+
+	case *add:
+		// When any arguments (excluding flags) are provided they will be
+		// used as the new task
+		t, err := getTask(os.Stdin, flag.Args()...)
+		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
-	case *delete > 0:
-		if err := l.Delete(*delete); err != nil {
+		// Add the task, as a subtask when a parent is given
+		var parent []int
+		if *sub != "" {
+			parent = address(*sub)
+		}
+
+		for _, task := range strings.Split(t, "\n") {
+			if err := j.AddAt(l, parent, task, opts...); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+
+		// Save the new list
+		save(s, l, j)
+
+	case *edit != "":
+		t, err := getTask(os.Stdin, flag.Args()...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if err := j.EditAt(l, address(*edit), t, opts...); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
 		// Save the new list
-		if err := l.Save(todoFilename); err != nil {
+		save(s, l, j)
+
+	case *undo:
+		e, err := j.Undo(l)
+		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
-	case *add:
-		// When any arguments (excluding flags) are provided they will be
-		// used as the new task
-		t, err := getTask(os.Stdin, flag.Args()...)
+		// Save the new list, and the archive if tasks moved in or out
+		if err := saveUndone(s, todoFilename, l, j, e, false); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Undone: %s\n", e.Describe(disp.timeLayout))
+
+	case *redo:
+		e, err := j.Redo(l)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
-		// Add the task
-		for _, task := range strings.Split(t, "\n") {
-			l.Add(task)
+		// Save the new list, and the archive if tasks moved in or out
+		if err := saveUndone(s, todoFilename, l, j, e, true); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Redone: %s\n", e.Describe(disp.timeLayout))
+
+	case *history:
+		entries, err := j.Entries()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		for k, e := range entries {
+			fmt.Printf("%d: %s\n", k+1, e.Describe(disp.timeLayout))
+		}
+
+	case *archived:
+		a, err := getArchive(todoFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if *search != "" {
+			// Search archived todo items, which are all completed
+			printMatches(a.Search(*search, true))
+			break
+		}
+
+		// List archived todo items with completed date/time
+		for k, t := range *a {
+			fmt.Printf("X %d: %s\t%s\n", k+1, t.Task, t.CompletedAt.Format(disp.timeLayout))
+		}
+
+	case *search != "":
+		// List matching todo items, best matches first
+		printMatches(l.Search(*search, *searchDone))
+
+	case *stats:
+		a, err := getArchive(todoFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Archived tasks count towards the statistics. They are all
+		// completed so the numbers of the open tasks are unchanged
+		all := append(*l, *a...)
+		st := all.Stats(time.Now(), 14)
+
+		if *format != "text" && *format != "json" {
+			fmt.Fprintf(os.Stderr, "Unknown statistics format %q\n", *format)
+			os.Exit(1)
+		}
+
+		if *format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(st); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			break
+		}
+		fmt.Print(st)
+
+	case *start != "":
+		if err := j.StartAt(l, address(*start), time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
 		// Save the new list
-		if err := l.Save(todoFilename); err != nil {
+		save(s, l, j)
+
+	case *stop != "":
+		if err := j.StopAt(l, address(*stop), time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Save the new list
+		save(s, l, j)
+
+	case *dep != "":
+		if err := j.DependAt(l, address(*dep), selection(l, *on)...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Save the new list
+		save(s, l, j)
+
+	case *undep != "":
+		if err := j.UndependAt(l, address(*undep), address(*on)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Save the new list
+		save(s, l, j)
+
+	case *ready:
+		// List the pending items that can be worked on
+		if err := printList(os.Stdout, l.ReadyRows(disp.sort), *verbose, disp, *format, *tmplFile); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
+	case *graph:
+		if *format != "text" && *format != "dot" {
+			fmt.Fprintf(os.Stderr, "Unknown graph format %q\n", *format)
+			os.Exit(1)
+		}
+
+		if err := l.Graph(os.Stdout, *format == "dot"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *report:
+		a, err := getArchive(todoFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Time worked on archived tasks is still reported
+		r := l.TimeReportWith(*a, time.Now())
+
+		switch *format {
+		case "text":
+			fmt.Print(r)
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(r); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown report format %q\n", *format)
+			os.Exit(1)
+		}
+
+	case *encrypt, *decrypt:
+		if err := setEncryption(s, l, *encrypt); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *move > 0:
+		if err := moveTask(l, j, *move, *to); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Save the new list
+		save(s, l, j)
+		fmt.Printf("Moved task %d to %s\n", *move, *to)
+
+	case *archive:
+		n, err := archiveTasks(s, l, j, *days)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Archived %d tasks\n", n)
+
+	case *restore > 0:
+		a, err := getArchive(todoFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if err := j.Restore(l, a, *restore); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// Save the list first so a failure never loses the task
+		commit(s, l, j)
+
+		if err := saveArchive(s, todoFilename, a); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *exportFmt != "":
+		if err := export(os.Stdout, l, *exportFmt, *group, *events); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case *importFmt != "":
+		items, err := importTasks(os.Stdin, *importFmt, flag.Args()...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		j.Import(l, items)
+
+		// Save the new list
+		save(s, l, j)
+
 	default:
 		// Invalid flag provided
 		fmt.Fprintln(os.Stderr, "Invalid option")
@@ -128,6 +735,639 @@ func main() {
 	}
 }
 
+// save function saves the list and then records the changes made
+// to it in the journal. When TODO_ARCHIVE_DAYS is set, old completed
+// tasks are archived along with the changes
+func save(s todo.Store, l *todo.List, j *todo.Journal) {
+	if archiveDays >= 0 {
+		if _, err := archiveTasks(s, l, j, archiveDays); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	commit(s, l, j)
+}
+
+// commit function saves the list and then records the
+// changes made to it in the journal
+func commit(s todo.Store, l *todo.List, j *todo.Journal) {
+	if err := s.Save(l); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := j.Commit(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// encrypted function reports whether the store saves the list encrypted
+func encrypted(s todo.Store) bool {
+	js, ok := s.(*todo.JSONStore)
+	return ok && js.Encrypt
+}
+
+// newJournal function returns the journal of the list file, with its
+// entries encrypted when the store saves the list encrypted. The list
+// must be loaded first
+func newJournal(s todo.Store, filename string) *todo.Journal {
+	j := todo.NewJournal(filename)
+	j.Encrypt = encrypted(s)
+
+	return j
+}
+
+// saveArchive function saves the archive of the list, encrypted
+// when the store saves the list encrypted
+func saveArchive(s todo.Store, filename string, a *todo.List) error {
+	as := &todo.JSONStore{Filename: todo.ArchiveFile(filename), Encrypt: encrypted(s)}
+	return as.Save(a)
+}
+
+// address function parses the address of a task, such as 3 or 3.2,
+// exiting with an error if it's invalid
+func address(addr string) []int {
+	path, err := todo.ParseAddress(addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return path
+}
+
+// selection function resolves the items selected by sel, followed
+// by any extra words of a filter expression given as arguments,
+// exiting with an error if it's invalid
+func selection(l *todo.List, sel string, args ...string) [][]int {
+	paths, err := l.Select(strings.Join(append([]string{sel}, args...), " "), time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return paths
+}
+
+// warnBlocked function warns about the selected items that
+// are blocked by pending items they depend on
+func warnBlocked(w io.Writer, l *todo.List, paths [][]int) {
+	for _, p := range paths {
+		blocked, err := l.BlockedBy(p)
+		if err != nil || len(blocked) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "Warning: task %s is blocked by %s\n", todo.FormatAddress(p), strings.Join(blocked, ", "))
+	}
+}
+
+// printList function prints the rows of the list with the
+// formatter chosen by the format and template flags
+func printList(w io.Writer, rows []todo.Row, verbose bool, d display, format, tmplFile string) error {
+	var f todo.Formatter
+	var err error
+
+	switch {
+	case tmplFile != "":
+		f, err = todo.NewTemplateFormatter(tmplFile)
+	case format == "text":
+		f = todo.PlainFormatter{Verbose: verbose, Color: d.color, TimeLayout: d.timeLayout, DateLayout: d.dateLayout}
+	case format == "table":
+		f = todo.TableFormatter{TimeLayout: d.timeLayout, DateLayout: d.dateLayout}
+	default:
+		f, err = todo.NewFormatter(format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return f.Format(w, rows)
+}
+
+// printMatches function prints the items found by a search
+func printMatches(matches []todo.Match) {
+	for _, m := range matches {
+		prefix := "  "
+		if m.Done {
+			prefix = "X "
+		}
+		fmt.Printf("%s%s: %s\n", prefix, m.Address, m.Task)
+	}
+}
+
+// getArchive function reads the archive kept next to the list
+func getArchive(filename string) (*todo.List, error) {
+	a := &todo.List{}
+	if err := a.Get(todo.ArchiveFile(filename)); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// archiveTasks function moves the tasks completed more than the
+// given number of days ago to the archive, saves the list along
+// with its journal and returns how many tasks were moved
+func archiveTasks(s todo.Store, l *todo.List, j *todo.Journal, days int) (int, error) {
+	a, err := getArchive(todoFilename)
+	if err != nil {
+		return 0, err
+	}
+
+	// Save the archive first so a failure never loses tasks
+	n := j.Archive(l, a, time.Now().AddDate(0, 0, -days))
+	if n > 0 {
+		if err := saveArchive(s, todoFilename, a); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.Save(l); err != nil {
+		return 0, err
+	}
+
+	return n, j.Commit()
+}
+
+// saveUndone function saves the list after the operation of entry e
+// was undone, or redone when redo is set, along with the archive when
+// the operation archived or restored tasks. Whichever receives the
+// tasks is saved first so a failure never loses them
+func saveUndone(s todo.Store, filename string, l *todo.List, j *todo.Journal, e todo.Entry, redo bool) error {
+	var a *todo.List
+	if e.Op == todo.OpArchive || e.Op == todo.OpRestore {
+		var err error
+		if a, err = getArchive(filename); err != nil {
+			return err
+		}
+	}
+
+	appended := a != nil && e.SyncArchive(a, redo)
+	if appended {
+		if err := saveArchive(s, filename, a); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Save(l); err != nil {
+		return err
+	}
+
+	if err := j.Commit(); err != nil {
+		return err
+	}
+
+	if a != nil && !appended {
+		return saveArchive(s, filename, a)
+	}
+
+	return nil
+}
+
+// initTodo function creates an empty list in the current directory
+func initTodo(name string) error {
+	if _, err := os.Stat(name); err == nil {
+		return fmt.Errorf("%s already exists", name)
+	}
+
+	l := &todo.List{}
+	if err := l.Save(name); err != nil {
+		return err
+	}
+
+	f, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created empty list %s\n", f)
+	return nil
+}
+
+// findTodo function returns the nearest list with the given name
+// in the current directory or its parents, and whether it was found.
+// When there is none, it returns fallback, or the user's global list
+// without one
+func findTodo(name, fallback string) (string, bool, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false, err
+	}
+
+	if f, ok := todo.FindList(dir, name); ok {
+		return f, true, nil
+	}
+
+	if fallback != "" {
+		return fallback, false, nil
+	}
+
+	f, err := todo.ListFile(todo.GlobalList)
+	return f, false, err
+}
+
+// mergeFiles function merges the base, ours and theirs list files
+// into ours, reporting any conflicts to w
+func mergeFiles(w io.Writer, files ...string) error {
+	if len(files) != 3 {
+		return fmt.Errorf("Merge requires the base, ours and theirs files")
+	}
+
+	// Our copy is read through a store so it stays encrypted if it is
+	ours := &todo.JSONStore{Filename: files[1]}
+
+	lists := make([]todo.List, 3)
+	for k, f := range files {
+		var err error
+		if k == 1 {
+			err = ours.Load(&lists[k])
+		} else {
+			err = lists[k].Get(f)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+	}
+
+	merged, conflicts := todo.Merge(lists[0], lists[1], lists[2])
+	if err := ours.Save(&merged); err != nil {
+		return err
+	}
+
+	for _, c := range conflicts {
+		fmt.Fprintf(w, "CONFLICT %s\n", c)
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d conflicts: kept our changes", len(conflicts))
+	}
+
+	return nil
+}
+
+// envPassphrase function reads the passphrase of encrypted lists from
+// the environment or the file it names only, never from the terminal
+func envPassphrase() ([]byte, error) {
+	if os.Getenv("TODO_PASSPHRASE") == "" && os.Getenv("TODO_PASSPHRASE_FILE") == "" {
+		return nil, todo.ErrNoPassphrase
+	}
+
+	return readPassphrase(false)
+}
+
+// readPassphrase function reads the passphrase of encrypted lists from
+// the environment, the file it names or the terminal. New passphrases
+// typed on the terminal are confirmed
+func readPassphrase(confirm bool) ([]byte, error) {
+	if p := os.Getenv("TODO_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+
+	if f := os.Getenv("TODO_PASSPHRASE_FILE"); f != "" {
+		p, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.TrimRight(string(p), "\r\n")), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("List is encrypted: set TODO_PASSPHRASE or TODO_PASSPHRASE_FILE")
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil || !confirm {
+		return p, err
+	}
+
+	fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+	again, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(again) != string(p) {
+		return nil, fmt.Errorf("Passphrases don't match")
+	}
+
+	return p, nil
+}
+
+// setEncryption function saves the list, its archive and its journal
+// encrypted or in plain text
+func setEncryption(s todo.Store, l *todo.List, encrypt bool) error {
+	if _, ok := s.(*todo.JSONStore); !ok {
+		return fmt.Errorf("Only JSON lists can be encrypted")
+	}
+
+	a, err := getArchive(todoFilename)
+	if err != nil {
+		return err
+	}
+
+	s.(*todo.JSONStore).Encrypt = encrypt
+
+	if err := s.Save(l); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(todo.ArchiveFile(todoFilename)); err == nil {
+		if err := saveArchive(s, todoFilename, a); err != nil {
+			return err
+		}
+	}
+
+	return newJournal(s, todoFilename).Rewrite()
+}
+
+// showStatus function prints the status of the list and
+// reports whether any task is overdue
+func showStatus(w io.Writer, filename, kind, format string) (bool, error) {
+	s, err := todo.NewStore(filename, kind)
+	if err != nil {
+		return false, err
+	}
+
+	l := &todo.List{}
+	if err := s.Load(l); err != nil {
+		return false, err
+	}
+
+	st := l.Status(time.Now())
+
+	switch format {
+	case "text":
+		fmt.Fprintln(w, st)
+	case "json":
+		if err := json.NewEncoder(w).Encode(st); err != nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("Unknown status format %q", format)
+	}
+
+	return st.Overdue > 0, nil
+}
+
+// showCandidates function prints the values offered by the
+// completion scripts, loading the list when they come from it
+func showCandidates(w io.Writer, kind, filename, storeKind string) error {
+	l := &todo.List{}
+
+	if kind == compTasks || kind == compTop || kind == compTags {
+		s, err := todo.NewStore(filename, storeKind)
+		if err != nil {
+			return err
+		}
+		if err := s.Load(l); err != nil {
+			return err
+		}
+	}
+
+	return printCandidates(w, kind, l)
+}
+
+// showReminders function prints the reminders due since they were
+// last shown. Only the file tracking them is locked, so reminders
+// run by overlapping cron jobs are shown once
+func showReminders(w io.Writer, filename, kind, dateLayout string) error {
+	remindFile := todo.RemindFile(filename)
+
+	lock, err := todo.Lock(remindFile)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	s, err := todo.NewStore(filename, kind)
+	if err != nil {
+		return err
+	}
+
+	l := &todo.List{}
+	if err := s.Load(l); err != nil {
+		return err
+	}
+
+	a, err := todo.GetAnnounced(remindFile)
+	if err != nil {
+		return err
+	}
+
+	rs := l.Reminders(time.Now(), a)
+	for _, r := range rs {
+		fmt.Fprintln(w, r.Describe(dateLayout))
+	}
+
+	a.Announce(l, rs)
+	return a.Save(remindFile)
+}
+
+// showLists function prints the named lists with their number
+// of tasks or, when all is set, the tasks of every list
+func showLists(w io.Writer, all, pending bool, d display) error {
+	names, err := todo.Lists()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		f, err := todo.ListFile(name)
+		if err != nil {
+			return err
+		}
+
+		l := &todo.List{}
+		if err := l.Get(f); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if !all {
+			open := 0
+			for _, t := range *l {
+				if !t.Done {
+					open++
+				}
+			}
+			fmt.Fprintf(w, "%s: %d tasks, %d pending\n", name, len(*l), open)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s:\n", name)
+		if err := printList(w, l.SortedRows(pending, d.sort), false, d, "text", ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveTask function moves item i of the list to the named list.
+// The other list is saved first so a failure never loses the task
+func moveTask(l *todo.List, j *todo.Journal, i int, name string) error {
+	if name == "" {
+		return fmt.Errorf("Use '-to' to name the list the task is moved to")
+	}
+
+	if i > len(*l) {
+		return fmt.Errorf("Item %d does not exist", i)
+	}
+
+	f, err := todo.ListFile(name)
+	if err != nil {
+		return err
+	}
+
+	if same, _ := filepath.Abs(todoFilename); same == f {
+		return fmt.Errorf("Task %d is already in list %s", i, name)
+	}
+
+	lock, err := todo.Lock(f)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Keep the other list encrypted only if it already is
+	ds := &todo.JSONStore{Filename: f}
+	dst := &todo.List{}
+	if err := ds.Load(dst); err != nil {
+		return err
+	}
+
+	t := (*l)[i-1]
+	if err := j.Delete(l, i); err != nil {
+		return err
+	}
+
+	dj := newJournal(ds, f)
+	dj.Import(dst, todo.List{t})
+
+	if err := ds.Save(dst); err != nil {
+		return err
+	}
+
+	return dj.Commit()
+}
+
+// taskOptions function validates the due date, recurrence
+// rule and notes flags and returns them as item options
+func taskOptions(due, recur, notes, remindAt string) ([]todo.Option, error) {
+	var opts []todo.Option
+	var d time.Time
+
+	if due != "" {
+		var err error
+		d, err = time.ParseInLocation("2006-01-02", due, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid due date %q: use YYYY-MM-DD", due)
+		}
+		opts = append(opts, todo.WithDue(d))
+	}
+
+	if remindAt != "" {
+		at, err := reminderTime(remindAt, d, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithReminder(at))
+	}
+
+	if recur != "" {
+		if _, err := todo.ParseRecurrence(recur); err != nil {
+			return nil, err
+		}
+		opts = append(opts, todo.WithRecurrence(recur))
+	}
+
+	if notes != "" {
+		opts = append(opts, todo.WithNotes(notes))
+	}
+
+	return opts, nil
+}
+
+// reminderTime function parses a reminder time. A time of day
+// alone is taken on the due date, or today without one
+func reminderTime(s string, due, now time.Time) (time.Time, error) {
+	if at, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local); err == nil {
+		return at, nil
+	}
+
+	clock, err := time.ParseInLocation("15:04", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid reminder time %q: use YYYY-MM-DD HH:MM or HH:MM", s)
+	}
+
+	if due.IsZero() {
+		due = now
+	}
+	y, m, d := due.Date()
+
+	return time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, time.Local), nil
+}
+
+// export function writes the list to w in the given format
+func export(w io.Writer, l *todo.List, format string, group, events bool) error {
+	switch format {
+	case "txt":
+		return l.ExportTxt(w)
+	case "md":
+		return l.ExportMarkdown(w, group)
+	case "ics":
+		return l.ExportICS(w, events)
+	}
+
+	return fmt.Errorf("Unknown export format %q", format)
+}
+
+// importTasks function reads tasks in the given format from the
+// files provided as arguments or from STDIN when there are none
+func importTasks(r io.Reader, format string, files ...string) (todo.List, error) {
+	var decode func(io.Reader) (todo.List, error)
+
+	switch format {
+	case "txt":
+		decode = todo.ImportTxt
+	case "md":
+		decode = todo.ImportMarkdown
+	case "ics":
+		decode = todo.ImportICS
+	default:
+		return nil, fmt.Errorf("Unknown import format %q", format)
+	}
+
+	if len(files) == 0 {
+		return decode(r)
+	}
+
+	items := todo.List{}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := decode(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, l...)
+	}
+
+	return items, nil
+}
+
 // getTask function decides where to get the description for a new
 // task from: arguments or STDIN
 func getTask(r io.Reader, args ...string) (string, error) {
@@ -1,12 +1,15 @@
 package main_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,6 +17,7 @@ import (
 var (
 	binName  = "todo"
 	fileName string
+	dataDir  string
 )
 
 func TestMain(m *testing.M) {
@@ -35,12 +39,35 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	// Tests use the list in this directory rather than one found
+	// in its parents or the global list
+	if err := os.WriteFile(fileName, nil, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Keep the named lists and the configuration away from the
+	// user's data and configuration directories
+	var err error
+	dataDir, err = os.MkdirTemp("", "todo-data")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("XDG_DATA_HOME", dataDir)
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dataDir, "config"))
+	os.Unsetenv("NO_COLOR")
+
 	fmt.Println("Running tests...")
 	result := m.Run()
 
 	fmt.Println("Clean up...")
 	os.Remove(binName)
 	os.Remove(fileName)
+	os.Remove(fileName + ".journal")
+	os.Remove(fileName + ".lock")
+	os.Remove(fileName + ".archive")
+	os.RemoveAll(dataDir)
 
 	os.Exit(result)
 }
@@ -144,6 +171,80 @@ func TestTodoCLI(t *testing.T) {
 		}
 	})
 
+	t.Run("UndoDelete", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-undo")
+
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := fmt.Sprintf("X 1: %s\n  2: %s\n", task, task2)
+
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+
+	t.Run("RedoDelete", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-redo")
+
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-history")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if len(lines) != 6 {
+			t.Errorf("Expected %d history entries, got %q instead\n", 6, string(out))
+		}
+	})
+
+	t.Run("ExportImportTxt", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-export", "txt")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := fmt.Sprintf("x %s %s %s\n", time.Now().Format("2006-01-02"),
+			time.Now().Format("2006-01-02"), task)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-import", "txt")
+		cmd.Stdin = strings.NewReader("(A) imported task +project\n")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = fmt.Sprintf("X 1: %s\n  2: imported task +project\n", task)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Remove the imported task again
+		if err := exec.Command(cmdPath, "-undo").Run(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
 	t.Run("VerboseTask", func(t *testing.T) {
 		cmd := exec.Command(cmdPath, "-v")
 		out, err := cmd.CombinedOutput()
@@ -157,4 +258,738 @@ func TestTodoCLI(t *testing.T) {
 			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
 		}
 	})
+	t.Run("CompleteRecurringTask", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-add", "-recur", "every=2d", "recurring task")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-complete", "2")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-p")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "  3: recurring task\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("ArchiveRestore", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-archive")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "  1: recurring task\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-archived", "-search", "number")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = fmt.Sprintf("X 1: %s\n", task)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-restore", "1")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = fmt.Sprintf("  1: recurring task\nX 2: %s\n", task)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Undoing the restore puts the task back in the archive
+		for _, step := range []struct {
+			args     []string
+			archived bool
+		}{
+			{[]string{"-undo"}, true},
+			{[]string{"-redo"}, false},
+		} {
+			cmd = exec.Command(cmdPath, step.args...)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+
+			cmd = exec.Command(cmdPath, "-archived")
+			out, err = cmd.CombinedOutput()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(out), task) != step.archived {
+				t.Errorf("Expected task archived %t after %v, got %q instead\n", step.archived, step.args, out)
+			}
+		}
+	})
+	t.Run("Subtasks", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-add", "-sub", "1", "subtask")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-complete", "1.1")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The recurring parent was completed, adding its next
+		// occurrence with the subtask pending again
+		expected := fmt.Sprintf("X 1: recurring task (1/1)\n  X 1.1: subtask\nX 2: %s\n  3: recurring task (0/1)\n    3.1: subtask\n", task)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Subtasks are found by their address
+		cmd = exec.Command(cmdPath, "-search", "subtask")
+		if out, err = cmd.CombinedOutput(); err != nil {
+			t.Fatal(err)
+		}
+
+		expected = "  3.1: subtask\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("StatsJSON", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-stats", "-format", "json")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var st struct {
+			Total   int
+			Pending int
+		}
+		if err := json.Unmarshal(out, &st); err != nil {
+			t.Fatal(err)
+		}
+
+		// Five tasks in the list, with the subtasks, and one left in the archive
+		if st.Total != 6 || st.Pending != 2 {
+			t.Errorf("Expected %d tasks and %d pending, got %+v instead\n", 6, 2, st)
+		}
+	})
+	t.Run("NamedLists", func(t *testing.T) {
+		for _, task := range []string{"review pull requests", "page the team"} {
+			cmd := exec.Command(cmdPath, "-l", "work", "-add", task)
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-l", "work", "-move", "2", "-to", "oncall")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-lists")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "oncall: 1 tasks, 1 pending\nwork: 1 tasks, 1 pending\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-all")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = "oncall:\n  1: page the team\nwork:\n  1: review pull requests\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("WhichList", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-which")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := filepath.Join(dir, fileName) + "\n"
+		if os.Getenv("TODO_FILENAME") != "" {
+			expected = fileName + "\n"
+		}
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Without a list nearby, the global list is used
+		project := t.TempDir()
+		sub := filepath.Join(project, "src")
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-which")
+		cmd.Dir = sub
+		cmd.Env = append(os.Environ(), "TODO_FILENAME=")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = filepath.Join(dataDir, "todo", "default.json") + "\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Once created, the project list is found from its subdirectories
+		cmd = exec.Command(cmdPath, "-init")
+		cmd.Dir = project
+		cmd.Env = append(os.Environ(), "TODO_FILENAME=")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-which")
+		cmd.Dir = sub
+		cmd.Env = append(os.Environ(), "TODO_FILENAME=")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = filepath.Join(project, ".todo.json") + "\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("ListFormats", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-list", "-format", "json")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rows []struct {
+			Address string
+			Task    string
+		}
+		if err := json.Unmarshal(out, &rows); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rows) != 5 || rows[1].Address != "1.1" || rows[1].Task != "subtask" {
+			t.Errorf("Unexpected rows %+v", rows)
+		}
+
+		cmd = exec.Command(cmdPath, "-p", "-format", "table")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "#") || !strings.Contains(lines[1], "recurring task") {
+			t.Errorf("Unexpected table %q", string(out))
+		}
+	})
+	t.Run("BulkOperations", func(t *testing.T) {
+		for _, task := range []string{"task A", "task B", "task C", "task D", "task E"} {
+			cmd := exec.Command(cmdPath, "-l", "sprint", "-add", task)
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, args := range [][]string{
+			{"-complete", "1-2,4"},
+			{"-del", "done"},
+		} {
+			cmd := exec.Command(cmdPath, append([]string{"-l", "sprint"}, args...)...)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-l", "sprint", "-list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "  1: task C\n  2: task E\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// All the deleted tasks come back with a single undo
+		cmd = exec.Command(cmdPath, "-l", "sprint", "-undo")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "sprint", "-p")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = "  3: task C\n  5: task E\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("TimeTracking", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-l", "billing", "-add", "fix login +web")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "billing", "-start", "1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "billing", "-list")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "  1: fix login +web [running, 0h00m]\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "billing", "-stop", "1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "billing", "-report", "-format", "json")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var r struct {
+			Tasks []struct{ Name string }
+			Tags  []struct{ Name string }
+		}
+		if err := json.Unmarshal(out, &r); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(r.Tasks) != 1 || r.Tasks[0].Name != "1: fix login +web" || len(r.Tags) != 1 || r.Tags[0].Name != "+web" {
+			t.Errorf("Unexpected report %s", out)
+		}
+	})
+	t.Run("EncryptedList", func(t *testing.T) {
+		env := append(os.Environ(), "TODO_PASSPHRASE=s3cret")
+
+		for _, args := range [][]string{
+			{"-add", "call ACME Corp"},
+			{"-encrypt"},
+			{"-add", "email Initech"},
+		} {
+			cmd := exec.Command(cmdPath, append([]string{"-l", "customers"}, args...)...)
+			cmd.Env = env
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+
+		f := filepath.Join(dataDir, "todo", "customers.json")
+		for _, name := range []string{f, f + ".journal"} {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "ACME") || strings.Contains(string(data), "Initech") {
+				t.Errorf("Expected %s to be encrypted", name)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-l", "customers", "-list")
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		expected := "  1: call ACME Corp\n  2: email Initech\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Without the passphrase the list can't be read
+		cmd = exec.Command(cmdPath, "-l", "customers", "-list")
+		cmd.Env = append(os.Environ(), "TODO_PASSPHRASE=wrong")
+		if err := cmd.Run(); err == nil {
+			t.Error("Expected error reading the list with a wrong passphrase")
+		}
+
+		// The status is shown in shell prompts, so it never asks for it
+		cmd = exec.Command(cmdPath, "-l", "customers", "-status")
+		out, err = cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(out), "no passphrase provided") {
+			t.Errorf("Expected missing passphrase error, got %q instead.", out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "customers", "-decrypt")
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "ACME") {
+			t.Errorf("Expected list in plain text, got %q", data)
+		}
+	})
+	t.Run("MergeLists", func(t *testing.T) {
+		tmp := t.TempDir()
+		env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(tmp, "base.json"))
+
+		for _, task := range []string{"task X", "task Y"} {
+			cmd := exec.Command(cmdPath, "-add", task)
+			cmd.Env = env
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Change two copies of the list independently
+		copies := map[string][]string{
+			"ours.json":   {"-complete", "1"},
+			"theirs.json": {"-add", "task Z"},
+		}
+		for name, args := range copies {
+			data, err := os.ReadFile(filepath.Join(tmp, "base.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(tmp, name), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(cmdPath, args...)
+			cmd.Env = append(os.Environ(), "TODO_FILENAME="+filepath.Join(tmp, name))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-merge", filepath.Join(tmp, "base.json"),
+			filepath.Join(tmp, "ours.json"), filepath.Join(tmp, "theirs.json"))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-list")
+		cmd.Env = append(os.Environ(), "TODO_FILENAME="+filepath.Join(tmp, "ours.json"))
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "X 1: task X\n  2: task Y\n  3: task Z\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("ExportImportICS", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-l", "calendar", "-add", "-due", "2024-05-01", "renew domain")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "calendar", "-export", "ics")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(out), "SUMMARY:renew domain\r\nDUE;VALUE=DATE:20240501\r\n") {
+			t.Errorf("Unexpected export %q", string(out))
+		}
+
+		ics := filepath.Join(t.TempDir(), "todo.ics")
+		if err := os.WriteFile(ics, out, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "subscribed", "-import", "ics", ics)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "subscribed", "-v")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(string(out), "  1: renew domain\t") || !strings.HasSuffix(string(out), "\tdue 2024-05-01\n") {
+			t.Errorf("Unexpected list %q", string(out))
+		}
+
+		// Importing the tasks back into their list doesn't duplicate them
+		cmd = exec.Command(cmdPath, "-l", "calendar", "-import", "ics", ics)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "calendar", "-list")
+		if out, err = cmd.CombinedOutput(); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := "  1: renew domain\n"; expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("Dependencies", func(t *testing.T) {
+		for _, task := range []string{"design", "build"} {
+			cmd := exec.Command(cmdPath, "-l", "deps", "-add", task)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-l", "deps", "-dep", "2", "-on", "1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "deps", "-dep", "1", "-on", "2")
+		if err := cmd.Run(); err == nil {
+			t.Error("Expected error adding a dependency cycle")
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "deps", "-ready")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "  1: design\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "deps", "-complete", "2")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		expected = "Warning: task 2 is blocked by 1\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "deps", "-graph")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected = "2: build\n  depends on 1: design (pending)\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+	})
+	t.Run("StatusReminders", func(t *testing.T) {
+		yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+
+		cmd := exec.Command(cmdPath, "-l", "alerts", "-add", "-due", yesterday, "-remind-at", "08:00", "pay rent")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "alerts", "-status")
+		out, err := cmd.Output()
+
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+			t.Errorf("Expected exit status 2 with overdue tasks, got %v instead", err)
+		}
+
+		expected := "1 pending, 0 due today, 1 overdue\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "alerts", "-remind")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		expected = "Reminder: 1: pay rent (due " + yesterday + ")\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Reminders are shown once
+		cmd = exec.Command(cmdPath, "-l", "alerts", "-remind")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		if len(out) != 0 {
+			t.Errorf("Expected no reminders, got %q instead\n", string(out))
+		}
+	})
+	t.Run("CompletionCandidates", func(t *testing.T) {
+		for _, task := range []string{"buy milk +home", "call bob @phone"} {
+			cmd := exec.Command(cmdPath, "-l", "shopping", "-add", task)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-l", "shopping", "-candidates", "tasks")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		expected := "1\tbuy milk +home\n2\tcall bob @phone\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "shopping", "-candidates", "tags")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		expected = "+home\n@phone\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		// Only top tasks can be moved to another list
+		cmd = exec.Command(cmdPath, "-l", "shopping", "-add", "-sub", "1", "oat milk")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		cmd = exec.Command(cmdPath, "-l", "shopping", "-candidates", "top-tasks")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		expected = "1\tbuy milk +home\n2\tcall bob @phone\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(out))
+		}
+
+		cmd = exec.Command(cmdPath, "-completion", "bash")
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+
+		if !strings.Contains(string(out), "complete -F _todo todo") {
+			t.Errorf("Unexpected script %q", string(out))
+		}
+	})
+	t.Run("Configuration", func(t *testing.T) {
+		userConfig := filepath.Join(dataDir, "config", "todo", "config.json")
+		if err := os.MkdirAll(filepath.Dir(userConfig), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(userConfig, []byte(`{"sort": "task", "date_format": "02/01/2006"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(userConfig)
+
+		project := t.TempDir()
+		projectConfig := filepath.Join(project, ".todo.config.json")
+		if err := os.WriteFile(projectConfig, []byte(`{"file": "tasks.json", "aliases": {"urgent": "-p -sort due"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Run the commands in the project without TODO_FILENAME
+		var env []string
+		for _, e := range os.Environ() {
+			if !strings.HasPrefix(e, "TODO_FILENAME=") {
+				env = append(env, e)
+			}
+		}
+		run := func(args ...string) string {
+			cmd := exec.Command(cmdPath, args...)
+			cmd.Dir, cmd.Env = project, env
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("%s: %s", err, out)
+			}
+			return string(out)
+		}
+
+		run("-add", "-due", "2024-05-02", "water plants")
+		run("-add", "-due", "2024-05-01", "pay rent")
+
+		if out := run("-list", "-v"); !strings.HasPrefix(out, "  2: pay rent\t") || !strings.HasSuffix(out, "\tdue 02/05/2024\n") {
+			t.Errorf("Expected list sorted by task with custom dates, got %q instead\n", out)
+		}
+
+		expected := "  2: pay rent\n  1: water plants\n"
+		if out := run("urgent"); out != expected {
+			t.Errorf("Expected %q, got %q instead\n", expected, out)
+		}
+
+		out := run("-config", "-sort", "priority")
+		for _, s := range []string{
+			filepath.Join(project, "tasks.json"),
+			"(project " + projectConfig + ")",
+			"02/01/2006",
+			"(user " + userConfig + ")",
+			"(flag -sort)",
+			"-p -sort due",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("Expected %q in configuration, got %q instead\n", s, out)
+			}
+		}
+	})
 }
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// errNotFound is returned when the requested task does not exist
+var errNotFound = errors.New("Task not found")
+
+// errPrecondition is returned when If-Match doesn't match the list
+var errPrecondition = errors.New("List changed: reload and try again")
+
+// task is the JSON representation of a ToDo item in the API
+type task struct {
+	Number      int        `json:"number"`
+	ID          string     `json:"id"`
+	Task        string     `json:"task"`
+	Done        bool       `json:"done"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Due         string     `json:"due,omitempty"`
+	Recur       string     `json:"recur,omitempty"`
+	Priority    string     `json:"priority,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+}
+
+// taskRequest is the body of requests creating or updating a task.
+// Fields left out of an update keep their current value, and fields
+// set to an empty string are cleared
+type taskRequest struct {
+	Task  *string `json:"task"`
+	Due   *string `json:"due"`
+	Recur *string `json:"recur"`
+	Notes *string `json:"notes"`
+}
+
+// server exposes a list file over HTTP. Every request locks the
+// file, so the API can be used alongside the CLI
+type server struct {
+	filename  string
+	storeKind string
+}
+
+// newServer returns the HTTP handler for the API
+func newServer(filename, storeKind string) http.Handler {
+	s := &server{filename: filename, storeKind: storeKind}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /todo", s.list)
+	mux.HandleFunc("POST /todo", s.add)
+	mux.HandleFunc("GET /todo/{number}", s.get)
+	mux.HandleFunc("PATCH /todo/{number}", s.update)
+	mux.HandleFunc("POST /todo/{number}/complete", s.complete)
+	mux.HandleFunc("DELETE /todo/{number}", s.delete)
+
+	return mux
+}
+
+// list replies with the tasks, optionally filtered by completion
+// status with ?done=true|false and ranked by a search with ?q=
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	var done *bool
+	if v := r.URL.Query().Get("done"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, fmt.Errorf("Invalid done filter %q", v))
+			return
+		}
+		done = &b
+	}
+
+	s.view(w, r, func(l *todo.List) (int, any, error) {
+		numbers := make([]int, 0, len(*l))
+
+		if q := r.URL.Query().Get("q"); q != "" {
+			for _, m := range l.Search(q, done == nil || *done) {
+				if len(m.Path) == 1 {
+					numbers = append(numbers, m.Path[0])
+				}
+			}
+		} else {
+			for k := range *l {
+				numbers = append(numbers, k+1)
+			}
+		}
+
+		tasks := []task{}
+		for _, n := range numbers {
+			if t := newTask(l, n); done == nil || t.Done == *done {
+				tasks = append(tasks, t)
+			}
+		}
+
+		return http.StatusOK, tasks, nil
+	})
+}
+
+// get replies with a single task
+func (s *server) get(w http.ResponseWriter, r *http.Request) {
+	s.view(w, r, func(l *todo.List) (int, any, error) {
+		n, err := taskNumber(r, l)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		return http.StatusOK, newTask(l, n), nil
+	})
+}
+
+// add creates a new task
+func (s *server) add(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		replyError(w, http.StatusBadRequest, fmt.Errorf("Invalid body: %w", err))
+		return
+	}
+
+	if req.Task == nil || *req.Task == "" {
+		replyError(w, http.StatusBadRequest, fmt.Errorf("Task cannot be blank"))
+		return
+	}
+
+	opts, err := req.options()
+	if err != nil {
+		replyError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.modify(w, r, func(l *todo.List, j *todo.Journal) (int, any, error) {
+		j.Add(l, *req.Task, opts...)
+
+		n := len(*l)
+		w.Header().Set("Location", fmt.Sprintf("/todo/%d", n))
+		return http.StatusCreated, newTask(l, n), nil
+	})
+}
+
+// update changes the fields of a task provided in the body
+func (s *server) update(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		replyError(w, http.StatusBadRequest, fmt.Errorf("Invalid body: %w", err))
+		return
+	}
+
+	if req.Task != nil && *req.Task == "" {
+		replyError(w, http.StatusBadRequest, fmt.Errorf("Task cannot be blank"))
+		return
+	}
+
+	opts, err := req.options()
+	if err != nil {
+		replyError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.modify(w, r, func(l *todo.List, j *todo.Journal) (int, any, error) {
+		n, err := taskNumber(r, l)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		desc := (*l)[n-1].Task
+		if req.Task != nil {
+			desc = *req.Task
+		}
+
+		if err := j.Edit(l, n, desc, opts...); err != nil {
+			return 0, nil, err
+		}
+
+		return http.StatusOK, newTask(l, n), nil
+	})
+}
+
+// complete marks a task as completed
+func (s *server) complete(w http.ResponseWriter, r *http.Request) {
+	s.modify(w, r, func(l *todo.List, j *todo.Journal) (int, any, error) {
+		n, err := taskNumber(r, l)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if err := j.Complete(l, n); err != nil {
+			return 0, nil, err
+		}
+
+		return http.StatusOK, newTask(l, n), nil
+	})
+}
+
+// delete removes a task
+func (s *server) delete(w http.ResponseWriter, r *http.Request) {
+	s.modify(w, r, func(l *todo.List, j *todo.Journal) (int, any, error) {
+		n, err := taskNumber(r, l)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if err := j.Delete(l, n); err != nil {
+			return 0, nil, err
+		}
+
+		return http.StatusNoContent, nil, nil
+	})
+}
+
+// view loads the list and replies with the result of fn.
+// It supports If-None-Match to skip sending unchanged lists
+func (s *server) view(w http.ResponseWriter, r *http.Request,
+	fn func(*todo.List) (int, any, error)) {
+
+	st, err := todo.NewStore(s.filename, s.storeKind)
+	if err != nil {
+		replyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	lock, err := todo.Lock(s.filename)
+	if err != nil {
+		replyError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer lock.Unlock()
+
+	l := &todo.List{}
+	if err := st.Load(l); err != nil {
+		replyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tag := etag(l)
+	w.Header().Set("ETag", tag)
+	if r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	status, body, err := fn(l)
+	reply(w, status, body, err)
+}
+
+// modify loads the list, applies fn and saves the result. Requests
+// with an If-Match header are rejected when the list has changed
+// since the client read it
+func (s *server) modify(w http.ResponseWriter, r *http.Request,
+	fn func(*todo.List, *todo.Journal) (int, any, error)) {
+
+	st, err := todo.NewStore(s.filename, s.storeKind)
+	if err != nil {
+		replyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	lock, err := todo.Lock(s.filename)
+	if err != nil {
+		replyError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer lock.Unlock()
+
+	l := &todo.List{}
+	if err := st.Load(l); err != nil {
+		replyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if m := r.Header.Get("If-Match"); m != "" && m != "*" && m != etag(l) {
+		replyError(w, http.StatusPreconditionFailed, errPrecondition)
+		return
+	}
+
+	j := newJournal(st, s.filename)
+	status, body, err := fn(l, j)
+	if err != nil {
+		reply(w, status, body, err)
+		return
+	}
+
+	if err := st.Save(l); err != nil {
+		replyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := j.Commit(); err != nil {
+		replyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(l))
+	reply(w, status, body, nil)
+}
+
+// options converts the request fields into item options
+func (req taskRequest) options() ([]todo.Option, error) {
+	var due, recur, notes string
+	if req.Due != nil {
+		due = *req.Due
+	}
+	if req.Recur != nil {
+		recur = *req.Recur
+	}
+	if req.Notes != nil {
+		notes = *req.Notes
+	}
+
+	opts, err := taskOptions(due, recur, notes, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Due != nil && due == "" {
+		opts = append(opts, todo.WithDue(time.Time{}))
+	}
+	if req.Recur != nil && recur == "" {
+		opts = append(opts, todo.WithRecurrence(""))
+	}
+	if req.Notes != nil && notes == "" {
+		opts = append(opts, todo.WithNotes(""))
+	}
+
+	return opts, nil
+}
+
+// newTask returns the API representation of task n
+func newTask(l *todo.List, n int) task {
+	t := (*l)[n-1]
+
+	resp := task{
+		Number:    n,
+		ID:        t.ID,
+		Task:      t.Task,
+		Done:      t.Done,
+		CreatedAt: t.CreatedAt,
+		Recur:     t.Recur,
+		Priority:  t.Priority,
+		Notes:     t.Notes,
+	}
+
+	if t.Done {
+		resp.CompletedAt = &t.CompletedAt
+	}
+
+	if !t.Due.IsZero() {
+		resp.Due = t.Due.Format("2006-01-02")
+	}
+
+	return resp
+}
+
+// taskNumber parses the task number from the request path
+func taskNumber(r *http.Request, l *todo.List) (int, error) {
+	n, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil || n <= 0 || n > len(*l) {
+		return 0, errNotFound
+	}
+
+	return n, nil
+}
+
+// etag returns a strong entity tag identifying the list contents
+func etag(l *todo.List) string {
+	js, _ := json.Marshal(l)
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", sha256.Sum256(js)))
+}
+
+// reply writes body as JSON with the given status code, or the
+// error if there is one
+func reply(w http.ResponseWriter, status int, body any, err error) {
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errNotFound) {
+			status = http.StatusNotFound
+		}
+		replyError(w, status, err)
+		return
+	}
+
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// replyError writes err as a JSON error message
+func replyError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupAPI starts a test server for a new list with two tasks
+func setupAPI(t *testing.T) string {
+	t.Helper()
+
+	ts := httptest.NewServer(newServer(filepath.Join(t.TempDir(), "todo.json"), ""))
+	t.Cleanup(ts.Close)
+
+	for _, body := range []string{`{"task":"Task 1"}`, `{"task":"Task 2","due":"2024-05-01"}`} {
+		r, err := http.Post(ts.URL+"/todo", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+
+		if r.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d instead.", http.StatusCreated, r.StatusCode)
+		}
+	}
+
+	return ts.URL
+}
+
+// do sends a request to the API and returns the response and its body
+func do(t *testing.T, method, url, body string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	out, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r, string(out)
+}
+
+// TestAPI tests the status codes and bodies of each endpoint
+func TestAPI(t *testing.T) {
+	testCases := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		content string
+	}{
+		{"ListAll", http.MethodGet, "/todo", "", http.StatusOK, `"task":"Task 2"`},
+		{"GetOne", http.MethodGet, "/todo/2", "", http.StatusOK, `"due":"2024-05-01"`},
+		{"GetMissing", http.MethodGet, "/todo/5", "", http.StatusNotFound, `"error"`},
+		{"AddBlank", http.MethodPost, "/todo", `{"task":""}`, http.StatusBadRequest, `"error"`},
+		{"AddBadDue", http.MethodPost, "/todo", `{"task":"x","due":"soon"}`, http.StatusBadRequest, `"error"`},
+		{"Update", http.MethodPatch, "/todo/1", `{"notes":"some notes"}`, http.StatusOK, `"notes":"some notes"`},
+		{"Complete", http.MethodPost, "/todo/1/complete", "", http.StatusOK, `"done":true`},
+		{"Delete", http.MethodDelete, "/todo/2", "", http.StatusNoContent, ""},
+		{"MethodNotAllowed", http.MethodPut, "/todo/1", "", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := setupAPI(t)
+
+			r, body := do(t, tc.method, url+tc.path, tc.body, nil)
+
+			if r.StatusCode != tc.status {
+				t.Errorf("Expected status %d, got %d instead.", tc.status, r.StatusCode)
+			}
+
+			if !strings.Contains(body, tc.content) {
+				t.Errorf("Expected body to contain %q, got %q instead.", tc.content, body)
+			}
+		})
+	}
+}
+
+// TestAPIClearFields tests an update clears the fields
+// set to an empty string
+func TestAPIClearFields(t *testing.T) {
+	url := setupAPI(t)
+
+	r, body := do(t, http.MethodPatch, url+"/todo/2", `{"recur":"daily","notes":"some notes"}`, nil)
+	if r.StatusCode != http.StatusOK || !strings.Contains(body, `"recur":"daily"`) {
+		t.Fatalf("Unexpected response %d: %s", r.StatusCode, body)
+	}
+
+	r, body = do(t, http.MethodPatch, url+"/todo/2", `{"due":"","recur":"","notes":""}`, nil)
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d instead.", http.StatusOK, r.StatusCode)
+	}
+
+	for _, field := range []string{`"due"`, `"recur"`, `"notes"`} {
+		if strings.Contains(body, field) {
+			t.Errorf("Expected %s cleared, got %s instead.", field, body)
+		}
+	}
+}
+
+// TestAPIFilters tests filtering and searching the listed tasks
+func TestAPIFilters(t *testing.T) {
+	url := setupAPI(t)
+	do(t, http.MethodPost, url+"/todo/1/complete", "", nil)
+
+	testCases := []struct {
+		query string
+		exp   []string
+	}{
+		{"", []string{"Task 1", "Task 2"}},
+		{"?done=true", []string{"Task 1"}},
+		{"?done=false", []string{"Task 2"}},
+		{"?q=task&done=false", []string{"Task 2"}},
+	}
+
+	for _, tc := range testCases {
+		r, body := do(t, http.MethodGet, url+"/todo"+tc.query, "", nil)
+		if r.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d instead.", http.StatusOK, r.StatusCode)
+		}
+
+		var tasks []task
+		if err := json.Unmarshal([]byte(body), &tasks); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tasks) != len(tc.exp) {
+			t.Fatalf("%s: expected %d tasks, got %d instead.", tc.query, len(tc.exp), len(tasks))
+		}
+
+		for k, exp := range tc.exp {
+			if tasks[k].Task != exp {
+				t.Errorf("%s: expected %q, got %q instead.", tc.query, exp, tasks[k].Task)
+			}
+		}
+	}
+}
+
+// TestAPIETag tests conditional requests for optimistic concurrency
+func TestAPIETag(t *testing.T) {
+	url := setupAPI(t)
+
+	r, _ := do(t, http.MethodGet, url+"/todo", "", nil)
+	tag := r.Header.Get("ETag")
+	if tag == "" {
+		t.Fatal("Expected ETag header")
+	}
+
+	r, _ = do(t, http.MethodGet, url+"/todo", "", map[string]string{"If-None-Match": tag})
+	if r.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected status %d, got %d instead.", http.StatusNotModified, r.StatusCode)
+	}
+
+	// The first update with the current tag succeeds and changes it
+	r, _ = do(t, http.MethodPost, url+"/todo/1/complete", "", map[string]string{"If-Match": tag})
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d instead.", http.StatusOK, r.StatusCode)
+	}
+	if r.Header.Get("ETag") == tag {
+		t.Errorf("Expected ETag to change after update")
+	}
+
+	// A second update based on the stale tag is rejected
+	r, _ = do(t, http.MethodDelete, url+"/todo/1", "", map[string]string{"If-Match": tag})
+	if r.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status %d, got %d instead.", http.StatusPreconditionFailed, r.StatusCode)
+	}
+}
@@ -0,0 +1,191 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigFile is the name of the project configuration file. The
+// nearest one in the current directory or its parents is used
+const ConfigFile = ".todo.config.json"
+
+// Config holds the settings of the configuration files. Unset
+// settings are left empty, and Sources records the file each
+// setting was read from, by its JSON name
+type Config struct {
+	File       string            `json:"file,omitempty"`
+	TimeFormat string            `json:"time_format,omitempty"`
+	DateFormat string            `json:"date_format,omitempty"`
+	Sort       string            `json:"sort,omitempty"`
+	Color      *bool             `json:"color,omitempty"`
+	Aliases    map[string]string `json:"aliases,omitempty"`
+
+	Sources map[string]string `json:"-"`
+}
+
+// ConfigDir returns the directory of the user configuration,
+// $XDG_CONFIG_HOME/todo or ~/.config/todo by default
+func ConfigDir() (string, error) {
+	if d := os.Getenv("XDG_CONFIG_HOME"); d != "" {
+		return filepath.Join(d, "todo"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config", "todo"), nil
+}
+
+// LoadConfig reads the user configuration file, config.json in
+// ConfigDir, and then the project configuration file nearest to
+// dir, whose settings take precedence. Missing files are skipped
+func LoadConfig(dir string) (Config, error) {
+	c := Config{Sources: map[string]string{}}
+
+	userDir, err := ConfigDir()
+	if err != nil {
+		return c, err
+	}
+
+	files := []string{filepath.Join(userDir, "config.json")}
+	if f, ok := FindList(dir, ConfigFile); ok {
+		files = append(files, f)
+	}
+
+	for _, f := range files {
+		fc, err := ReadConfig(f)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return c, err
+		}
+		c.merge(fc, f)
+	}
+
+	return c, nil
+}
+
+// ReadConfig reads and validates a configuration file. A relative
+// list file is taken from the directory of the configuration file
+func ReadConfig(filename string) (Config, error) {
+	var c Config
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return c, err
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("Invalid configuration file %s: %w", filename, err)
+	}
+
+	if c.Sort != "" {
+		if err := CheckSortOrder(c.Sort); err != nil {
+			return c, fmt.Errorf("Invalid configuration file %s: %w", filename, err)
+		}
+	}
+
+	for name := range c.Aliases {
+		if name == "" || strings.HasPrefix(name, "-") {
+			return c, fmt.Errorf("Invalid configuration file %s: invalid alias %q", filename, name)
+		}
+	}
+
+	if c.File != "" {
+		c.File = expandHome(c.File)
+		if !filepath.IsAbs(c.File) {
+			c.File = filepath.Join(filepath.Dir(filename), c.File)
+		}
+	}
+
+	return c, nil
+}
+
+// merge sets the settings of o read from the file source, keeping
+// the aliases it doesn't redefine
+func (c *Config) merge(o Config, source string) {
+	if o.File != "" {
+		c.File = o.File
+		c.Sources["file"] = source
+	}
+	if o.TimeFormat != "" {
+		c.TimeFormat = o.TimeFormat
+		c.Sources["time_format"] = source
+	}
+	if o.DateFormat != "" {
+		c.DateFormat = o.DateFormat
+		c.Sources["date_format"] = source
+	}
+	if o.Sort != "" {
+		c.Sort = o.Sort
+		c.Sources["sort"] = source
+	}
+	if o.Color != nil {
+		c.Color = o.Color
+		c.Sources["color"] = source
+	}
+
+	for name, args := range o.Aliases {
+		if c.Aliases == nil {
+			c.Aliases = map[string]string{}
+		}
+		c.Aliases[name] = args
+		c.Sources["aliases."+name] = source
+	}
+}
+
+// FromProject reports whether the setting was read from a project
+// configuration file rather than the user one
+func (c Config) FromProject(name string) bool {
+	src, ok := c.Sources[name]
+	return ok && filepath.Base(src) == ConfigFile
+}
+
+// Alias expands args when the first one is an alias, returning
+// them unchanged otherwise. Aliases are not expanded recursively
+func (c Config) Alias(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+
+	expansion, ok := c.Aliases[args[0]]
+	if !ok {
+		return args
+	}
+
+	return append(strings.Fields(expansion), args[1:]...)
+}
+
+// AliasNames returns the names of the aliases, sorted
+func (c Config) AliasNames() []string {
+	names := make([]string, 0, len(c.Aliases))
+	for name := range c.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/' && rest[0] != filepath.Separator) {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, rest)
+}
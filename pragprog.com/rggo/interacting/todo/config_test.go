@@ -0,0 +1,122 @@
+package todo_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// writeConfig writes a configuration file, creating its directory
+func writeConfig(t *testing.T, filename, data string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLoadConfig tests project settings take precedence over user ones
+func TestLoadConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	userFile := filepath.Join(home, "todo", "config.json")
+	writeConfig(t, userFile, `{
+		"file": "/var/todo/all.json",
+		"time_format": "02/01 15:04",
+		"sort": "due",
+		"aliases": {"today": "-p -sort due", "done": "-complete"}
+	}`)
+
+	project := t.TempDir()
+	projectFile := filepath.Join(project, todo.ConfigFile)
+	writeConfig(t, projectFile, `{
+		"file": "tasks.json",
+		"sort": "priority",
+		"color": true,
+		"aliases": {"today": "-p"}
+	}`)
+
+	dir := filepath.Join(project, "src", "cmd")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := todo.LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := filepath.Join(project, "tasks.json"); c.File != exp {
+		t.Errorf("Expected file %q, got %q instead.", exp, c.File)
+	}
+	if c.TimeFormat != "02/01 15:04" || c.Sort != "priority" || c.Color == nil || !*c.Color {
+		t.Errorf("Unexpected settings %+v", c)
+	}
+
+	sources := map[string]string{
+		"file":          projectFile,
+		"time_format":   userFile,
+		"sort":          projectFile,
+		"color":         projectFile,
+		"aliases.today": projectFile,
+		"aliases.done":  userFile,
+	}
+	if !reflect.DeepEqual(c.Sources, sources) {
+		t.Errorf("Expected sources %v, got %v instead.", sources, c.Sources)
+	}
+
+	args := c.Alias([]string{"today", "-format", "table"})
+	if exp := []string{"-p", "-format", "table"}; !reflect.DeepEqual(args, exp) {
+		t.Errorf("Expected %v, got %v instead.", exp, args)
+	}
+	if args := c.Alias([]string{"-list"}); !reflect.DeepEqual(args, []string{"-list"}) {
+		t.Errorf("Expected arguments unchanged, got %v instead.", args)
+	}
+	if names := c.AliasNames(); !reflect.DeepEqual(names, []string{"done", "today"}) {
+		t.Errorf("Unexpected aliases %v", names)
+	}
+}
+
+// TestLoadConfigMissing tests missing configuration files are skipped
+func TestLoadConfigMissing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	c, err := todo.LoadConfig(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.File != "" || c.Sort != "" || c.Color != nil || len(c.Sources) != 0 {
+		t.Errorf("Expected empty configuration, got %+v instead.", c)
+	}
+}
+
+// TestReadConfigInvalid tests invalid configuration files are rejected
+func TestReadConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{"Syntax", `{"sort": `},
+		{"Sort", `{"sort": "random"}`},
+		{"Alias", `{"aliases": {"-p": "-list"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), todo.ConfigFile)
+			writeConfig(t, filename, tc.data)
+
+			if _, err := todo.ReadConfig(filename); err == nil {
+				t.Error("Expected error reading an invalid configuration")
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Passphrase returns the passphrase the keys encrypting list files
+// are derived from. It's called once per process at most
+var Passphrase func() ([]byte, error)
+
+var (
+	// ErrNoPassphrase is returned when a file is encrypted
+	// but Passphrase is not set
+	ErrNoPassphrase = errors.New("List is encrypted: no passphrase provided")
+	// ErrDecrypt is returned when a file can't be decrypted
+	ErrDecrypt = errors.New("Cannot decrypt list: wrong passphrase or corrupted file")
+)
+
+const (
+	// encMagic starts every encrypted file, followed by the
+	// salt, the nonce and the sealed data
+	encMagic = "TODOENC1"
+	// encPrefix starts every encrypted journal entry
+	encPrefix = "enc:"
+
+	saltSize = 16
+	keySize  = 32
+)
+
+// keyring caches the passphrase and the keys derived from it, as
+// the key derivation is deliberately slow
+var keyring struct {
+	sync.Mutex
+	pass []byte
+	keys map[string][]byte
+}
+
+// passphrase returns the passphrase, asking for it the first time
+func passphrase() ([]byte, error) {
+	if keyring.pass != nil {
+		return keyring.pass, nil
+	}
+
+	if Passphrase == nil {
+		return nil, ErrNoPassphrase
+	}
+
+	pass, err := Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("Passphrase cannot be blank")
+	}
+
+	keyring.pass = pass
+	return pass, nil
+}
+
+// deriveKey returns the key derived from the passphrase with the salt
+func deriveKey(salt []byte) ([]byte, error) {
+	keyring.Lock()
+	defer keyring.Unlock()
+
+	if k, ok := keyring.keys[string(salt)]; ok {
+		return k, nil
+	}
+
+	pass, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := scrypt.Key(pass, salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyring.keys == nil {
+		keyring.keys = map[string][]byte{}
+	}
+	keyring.keys[string(salt)] = k
+
+	return k, nil
+}
+
+// newSalt returns a random salt
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// seal encrypts data with the key derived from salt, returning
+// the salt, a random nonce and the sealed data
+func seal(salt, data []byte) ([]byte, error) {
+	key, err := deriveKey(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(append([]byte{}, salt...), nonce...)
+	return gcm.Seal(out, nonce, data, nil), nil
+}
+
+// open decrypts data sealed by seal
+func open(sealed []byte) ([]byte, error) {
+	if len(sealed) < saltSize {
+		return nil, ErrDecrypt
+	}
+
+	salt, sealed := sealed[:saltSize], sealed[saltSize:]
+	key, err := deriveKey(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	data, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return data, nil
+}
+
+// newGCM returns the AES-GCM cipher for the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encrypt encrypts the contents of a list file with a new salt
+func encrypt(data []byte) ([]byte, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := seal(salt, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(encMagic), sealed...), nil
+}
+
+// decrypt decrypts the contents of a list file, returning
+// them unchanged if they're not encrypted
+func decrypt(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encMagic)) {
+		return data, nil
+	}
+
+	return open(data[len(encMagic):])
+}
+
+// IsEncrypted reports whether the list file is encrypted
+func IsEncrypted(filename string) (bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(encMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil
+	}
+
+	return string(magic) == encMagic, nil
+}
+
+// sealEntry encrypts a journal entry into a line, with the salt
+// already used by the journal so reading it derives a single key
+func (j *Journal) sealEntry(js []byte) ([]byte, error) {
+	if j.salt == nil {
+		salt, err := j.firstSalt()
+		if err != nil {
+			return nil, err
+		}
+		if salt == nil {
+			if salt, err = newSalt(); err != nil {
+				return nil, err
+			}
+		}
+		j.salt = salt
+	}
+
+	sealed, err := seal(j.salt, js)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(encPrefix + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// openEntry decrypts a journal line, returning it unchanged if
+// it's not encrypted
+func openEntry(line []byte) ([]byte, error) {
+	if !bytes.HasPrefix(line, []byte(encPrefix)) {
+		return line, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(line[len(encPrefix):]))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return open(sealed)
+}
+
+// firstSalt returns the salt of the first encrypted entry of the
+// journal, or nil if there is none
+func (j *Journal) firstSalt() ([]byte, error) {
+	f, err := os.Open(j.filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for s.Scan() {
+		line, ok := strings.CutPrefix(s.Text(), encPrefix)
+		if !ok {
+			continue
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(sealed) < saltSize {
+			return nil, ErrDecrypt
+		}
+		return sealed[:saltSize], nil
+	}
+
+	return nil, s.Err()
+}
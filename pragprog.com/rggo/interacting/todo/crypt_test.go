@@ -0,0 +1,168 @@
+package todo_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// usePassphrase sets the passphrase of encrypted files
+// for the duration of the test
+func usePassphrase(t *testing.T, pass string) {
+	t.Helper()
+
+	todo.ResetKeyring()
+	todo.Passphrase = func() ([]byte, error) { return []byte(pass), nil }
+
+	t.Cleanup(func() {
+		todo.ResetKeyring()
+		todo.Passphrase = nil
+	})
+}
+
+// TestEncryptedList tests saving and reading encrypted list files
+func TestEncryptedList(t *testing.T) {
+	tf := filepath.Join(t.TempDir(), "todo.json")
+	usePassphrase(t, "correct horse")
+
+	l := todo.List{}
+	l.Add("Call ACME Corp")
+	if err := l.SaveEncrypted(tf); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("ACME")) {
+		t.Errorf("Expected task to be encrypted, got %q", data)
+	}
+
+	if enc, err := todo.IsEncrypted(tf); err != nil || !enc {
+		t.Errorf("Expected file to be encrypted, got %t: %v", enc, err)
+	}
+
+	l2 := todo.List{}
+	if err := l2.Get(tf); err != nil {
+		t.Fatal(err)
+	}
+	if len(l2) != 1 || l2[0].Task != l[0].Task {
+		t.Errorf("Expected %v, got %v instead.", l, l2)
+	}
+
+	// Saving the list read keeps it encrypted
+	l2.Add("Email Initech")
+	if err := l2.Save(tf); err != nil {
+		t.Fatal(err)
+	}
+	if enc, err := todo.IsEncrypted(tf); err != nil || !enc {
+		t.Errorf("Expected file to stay encrypted, got %t: %v", enc, err)
+	}
+
+	// Reading needs the right passphrase
+	usePassphrase(t, "wrong horse")
+	if err := l2.Get(tf); !errors.Is(err, todo.ErrDecrypt) {
+		t.Errorf("Expected %q, got %v instead.", todo.ErrDecrypt, err)
+	}
+
+	todo.ResetKeyring()
+	todo.Passphrase = nil
+	if err := l2.Get(tf); !errors.Is(err, todo.ErrNoPassphrase) {
+		t.Errorf("Expected %q, got %v instead.", todo.ErrNoPassphrase, err)
+	}
+
+	// Plain files are read without a passphrase
+	plain := filepath.Join(t.TempDir(), "plain.json")
+	if err := l.Save(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := l2.Get(plain); err != nil {
+		t.Errorf("Expected plain file to be read, got %v instead.", err)
+	}
+}
+
+// TestEncryptedJournal tests the journal entries are encrypted
+// and can be rewritten in plain text
+func TestEncryptedJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	usePassphrase(t, "correct horse")
+
+	l := todo.List{}
+	for _, task := range []string{"Call ACME Corp", "Email Initech"} {
+		// A journal per task, like separate invocations of the tool
+		j := todo.NewJournal(filename)
+		j.Encrypt = true
+		j.Add(&l, task)
+		if err := j.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(todo.JournalFile(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("ACME")) {
+		t.Errorf("Expected journal to be encrypted, got %q", data)
+	}
+
+	j := todo.NewJournal(filename)
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Changes[0].After.Task != "Email Initech" {
+		t.Fatalf("Unexpected entries %v", entries)
+	}
+
+	if err := j.Rewrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = os.ReadFile(todo.JournalFile(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("ACME")) {
+		t.Errorf("Expected journal in plain text, got %q", data)
+	}
+}
+
+// TestJSONStoreEncrypted tests a store keeps an encrypted
+// file encrypted, whatever other stores do
+func TestJSONStoreEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	usePassphrase(t, "correct horse")
+
+	enc := &todo.JSONStore{Filename: filepath.Join(dir, "secret.json"), Encrypt: true}
+	plain := &todo.JSONStore{Filename: filepath.Join(dir, "plain.json")}
+
+	l := todo.List{}
+	l.Add("Call ACME Corp")
+	for _, s := range []*todo.JSONStore{enc, plain} {
+		if err := s.Save(&l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A new store learns the file is encrypted when loading it
+	s := &todo.JSONStore{Filename: enc.Filename}
+	if err := s.Load(&l); err != nil {
+		t.Fatal(err)
+	}
+	l.Add("Email Initech")
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exp := range map[string]bool{enc.Filename: true, plain.Filename: false} {
+		if got, err := todo.IsEncrypted(name); err != nil || got != exp {
+			t.Errorf("Expected %s encrypted %t, got %t: %v", name, exp, got, err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package todo
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// node is an item of the list, or one of its subtasks, found by ID
+type node struct {
+	path []int
+	t    item
+}
+
+// nodes indexes the items of the list and their subtasks by ID
+func (l *List) nodes() map[string]node {
+	m := map[string]node{}
+	l.walk(nil, func(path []int, t item) {
+		m[t.ID] = node{path: path, t: t}
+	})
+
+	return m
+}
+
+// DependAt makes the item at path depend on the item at on, so it's
+// blocked until that one is completed. Dependencies that would make
+// an item depend on itself, directly or not, are rejected
+func (l *List) DependAt(path, on []int) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	dls, dk, err := l.at(on)
+	if err != nil {
+		return err
+	}
+
+	t, dep := &(*ls)[k], (*dls)[dk]
+	if slices.Contains(t.Deps, dep.ID) {
+		return nil
+	}
+
+	t.Deps = append(t.Deps, dep.ID)
+	if cycle := l.cycle(); cycle != nil {
+		t.Deps = t.Deps[:len(t.Deps)-1]
+		return fmt.Errorf("Item %s cannot depend on item %s: cycle %s",
+			FormatAddress(path), FormatAddress(on), strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// UndependAt removes the dependency of the item at path on the item at on
+func (l *List) UndependAt(path, on []int) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	dls, dk, err := l.at(on)
+	if err != nil {
+		return err
+	}
+
+	t, id := &(*ls)[k], (*dls)[dk].ID
+	if !slices.Contains(t.Deps, id) {
+		return fmt.Errorf("Item %s does not depend on item %s", FormatAddress(path), FormatAddress(on))
+	}
+
+	t.Deps = slices.DeleteFunc(t.Deps, func(d string) bool { return d == id })
+	if len(t.Deps) == 0 {
+		t.Deps = nil
+	}
+
+	return nil
+}
+
+// DependAt makes the item at path depend on the items at on and
+// records the operation. No dependency is added if any is rejected
+func (j *Journal) DependAt(l *List, path []int, on ...[]int) error {
+	return j.updateAt(OpDepend, l, path, func() error {
+		saved := (*l)[path[0]-1].clone()
+		for _, o := range on {
+			if err := l.DependAt(path, o); err != nil {
+				(*l)[path[0]-1] = saved
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UndependAt removes a dependency of the item at path and records the operation
+func (j *Journal) UndependAt(l *List, path, on []int) error {
+	return j.updateAt(OpUndepend, l, path, func() error { return l.UndependAt(path, on) })
+}
+
+// BlockedBy returns the addresses of the pending items the item at
+// path depends on. Dependencies on deleted items are ignored
+func (l *List) BlockedBy(path []int) ([]string, error) {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.blockers((*ls)[k], l.nodes()), nil
+}
+
+// blockers returns the addresses of the pending items t depends on
+func (l *List) blockers(t item, nodes map[string]node) []string {
+	var addrs []string
+
+	for _, id := range t.Deps {
+		if n, ok := nodes[id]; ok && !n.t.Done {
+			addrs = append(addrs, FormatAddress(n.path))
+		}
+	}
+
+	return addrs
+}
+
+// ReadyRows returns the rows of the pending items that are not
+// blocked by any other item, in the given sort order
+func (l *List) ReadyRows(order string) []Row {
+	var ready []Row
+	for _, r := range l.SortedRows(true, order) {
+		if len(r.BlockedBy) == 0 {
+			ready = append(ready, r)
+		}
+	}
+
+	return ready
+}
+
+// Ready prints out the pending items that are not blocked
+func (l *List) Ready() string {
+	var b strings.Builder
+	PlainFormatter{}.Format(&b, l.ReadyRows(SortCreated))
+
+	return b.String()
+}
+
+// cycle returns the addresses of the items forming a dependency
+// cycle, or nil if there is none
+func (l *List) cycle() []string {
+	nodes := l.nodes()
+
+	// Depth first search, keeping the items being visited on a stack
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var stack []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		n, ok := nodes[id]
+		if !ok {
+			return nil
+		}
+
+		switch state[id] {
+		case visiting:
+			start := slices.Index(stack, id)
+			cycle := append(slices.Clone(stack[start:]), id)
+			for k, c := range cycle {
+				cycle[k] = FormatAddress(nodes[c].path)
+			}
+			return cycle
+		case visited:
+			return nil
+		}
+
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, d := range n.t.Deps {
+			if c := visit(d); c != nil {
+				return c
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+
+		return nil
+	}
+
+	var ids []string
+	l.walk(nil, func(path []int, t item) { ids = append(ids, t.ID) })
+
+	for _, id := range ids {
+		if c := visit(id); c != nil {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// Graph writes the dependency graph of the list to w, as text or,
+// when dot is true, in the Graphviz DOT language. Lists with a
+// dependency cycle, such as merged ones, are rejected
+func (l *List) Graph(w io.Writer, dot bool) error {
+	if cycle := l.cycle(); cycle != nil {
+		return fmt.Errorf("Dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	nodes := l.nodes()
+	if dot {
+		return l.graphDOT(w, nodes)
+	}
+
+	var err error
+	l.walk(nil, func(path []int, t item) {
+		if len(t.Deps) == 0 || err != nil {
+			return
+		}
+
+		_, err = fmt.Fprintf(w, "%s: %s\n", FormatAddress(path), t.Task)
+		for _, id := range t.Deps {
+			n, ok := nodes[id]
+			if !ok || err != nil {
+				continue
+			}
+
+			status := "pending"
+			if n.t.Done {
+				status = "done"
+			}
+			_, err = fmt.Fprintf(w, "  depends on %s: %s (%s)\n", FormatAddress(n.path), n.t.Task, status)
+		}
+	})
+
+	return err
+}
+
+// graphDOT writes the dependency graph in the DOT language,
+// with edges from each prerequisite to the items depending on it
+func (l *List) graphDOT(w io.Writer, nodes map[string]node) error {
+	var b strings.Builder
+
+	b.WriteString("digraph todo {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	l.walk(nil, func(path []int, t item) {
+		style := ""
+		if t.Done {
+			style = `, style=filled, fillcolor=lightgray`
+		}
+		fmt.Fprintf(&b, "  %q [label=%q%s];\n", t.ID, FormatAddress(path)+": "+t.Task, style)
+	})
+
+	l.walk(nil, func(path []int, t item) {
+		for _, id := range t.Deps {
+			if _, ok := nodes[id]; ok {
+				fmt.Fprintf(&b, "  %q -> %q;\n", id, t.ID)
+			}
+		}
+	})
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
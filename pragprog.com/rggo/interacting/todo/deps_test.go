@@ -0,0 +1,206 @@
+package todo_test
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// depsList returns a list where 3 depends on 1 and 2, and 4 on 3
+func depsList(t *testing.T) todo.List {
+	t.Helper()
+
+	l := todo.List{}
+	for _, task := range []string{"Design", "Buy parts", "Build", "Ship"} {
+		l.Add(task)
+	}
+
+	for _, d := range [][2]int{{3, 1}, {3, 2}, {4, 3}} {
+		if err := l.DependAt([]int{d[0]}, []int{d[1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return l
+}
+
+// TestDepend tests adding and removing dependencies
+func TestDepend(t *testing.T) {
+	l := depsList(t)
+
+	blocked, err := l.BlockedBy([]int{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(blocked, []string{"1", "2"}) {
+		t.Errorf("Expected item 3 blocked by %v, got %v instead.", []string{"1", "2"}, blocked)
+	}
+
+	// Completed items don't block
+	l.Complete(1)
+	blocked, _ = l.BlockedBy([]int{3})
+	if !slices.Equal(blocked, []string{"2"}) {
+		t.Errorf("Expected item 3 blocked by %v, got %v instead.", []string{"2"}, blocked)
+	}
+
+	if err := l.UndependAt([]int{3}, []int{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.UndependAt([]int{3}, []int{2}); err == nil {
+		t.Error("Expected error removing a missing dependency")
+	}
+	if blocked, _ = l.BlockedBy([]int{3}); len(blocked) != 0 {
+		t.Errorf("Expected item 3 not blocked, got %v instead.", blocked)
+	}
+
+	// Dependencies follow items when addresses change
+	l.Delete(1)
+	if blocked, _ = l.BlockedBy([]int{3}); !slices.Equal(blocked, []string{"2"}) {
+		t.Errorf("Expected item 3 blocked by %v, got %v instead.", []string{"2"}, blocked)
+	}
+}
+
+// TestDependCycle tests dependencies forming a cycle are rejected
+func TestDependCycle(t *testing.T) {
+	l := depsList(t)
+
+	testCases := []struct {
+		name     string
+		path, on []int
+	}{
+		{"Self", []int{1}, []int{1}},
+		{"Direct", []int{3}, []int{4}},
+		{"Indirect", []int{1}, []int{4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := l.DependAt(tc.path, tc.on)
+			if err == nil {
+				t.Fatal("Expected error adding a dependency cycle")
+			}
+			if !strings.Contains(err.Error(), "cycle") {
+				t.Errorf("Expected cycle error, got %q instead.", err)
+			}
+		})
+	}
+
+	// Rejected dependencies are not kept
+	var b strings.Builder
+	if err := l.Graph(&b, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReady tests listing the items that are not blocked
+func TestReady(t *testing.T) {
+	l := depsList(t)
+
+	rows := l.ReadyRows(todo.SortCreated)
+	var addrs []string
+	for _, r := range rows {
+		addrs = append(addrs, r.Address)
+	}
+	if !slices.Equal(addrs, []string{"1", "2"}) {
+		t.Errorf("Expected ready items %v, got %v instead.", []string{"1", "2"}, addrs)
+	}
+
+	l.Complete(1)
+	l.Complete(2)
+
+	expected := "  3: Build\n"
+	if out := l.Ready(); out != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, out)
+	}
+
+	if !strings.Contains(l.Pend(), "4: Ship [blocked by 3]") {
+		t.Errorf("Expected blocked item in listing, got %q instead.", l.Pend())
+	}
+}
+
+// TestGraph tests printing the dependency graph
+func TestGraph(t *testing.T) {
+	l := depsList(t)
+	l.Complete(1)
+
+	var b strings.Builder
+	if err := l.Graph(&b, false); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `3: Build
+  depends on 1: Design (done)
+  depends on 2: Buy parts (pending)
+4: Ship
+  depends on 3: Build (pending)
+`
+	if b.String() != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, b.String())
+	}
+
+	b.Reset()
+	if err := l.Graph(&b, true); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := b.String()
+	for _, s := range []string{
+		"digraph todo {",
+		`"` + l[0].ID + `" -> "` + l[2].ID + `";`,
+		`"` + l[2].ID + `" -> "` + l[3].ID + `";`,
+		"fillcolor=lightgray",
+	} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("Expected %q in DOT output, got %q instead.", s, dot)
+		}
+	}
+}
+
+// TestGraphCycle tests a cycle in a list file is reported
+func TestGraphCycle(t *testing.T) {
+	l := depsList(t)
+	l[0].Deps = []string{l[3].ID}
+
+	var b strings.Builder
+	err := l.Graph(&b, false)
+	if err == nil {
+		t.Fatal("Expected error printing a graph with a cycle")
+	}
+	if !strings.Contains(err.Error(), "1 -> 4 -> 3 -> 1") {
+		t.Errorf("Expected cycle 1 -> 4 -> 3 -> 1, got %q instead.", err)
+	}
+}
+
+// TestDependJournal tests undoing a dependency
+func TestDependJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	l := todo.List{}
+	j := todo.NewJournal(filename)
+	j.Add(&l, "Design")
+	j.Add(&l, "Build")
+
+	if err := j.DependAt(&l, []int{2}, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := j.Undo(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Op != todo.OpDepend || len(l[1].Deps) != 0 {
+		t.Errorf("Expected dependency undone, got %s with %v instead.", e.Op, l[1].Deps)
+	}
+	// Rejecting one of the dependencies rejects them all
+	j.Add(&l, "Ship")
+	j.DependAt(&l, []int{1}, []int{2})
+	if err := j.DependAt(&l, []int{2}, []int{3}, []int{1}); err == nil {
+		t.Fatal("Expected error adding a dependency cycle")
+	}
+	if len(l[1].Deps) != 0 {
+		t.Errorf("Expected no dependencies added, got %v instead.", l[1].Deps)
+	}
+}
@@ -0,0 +1,8 @@
+package todo
+
+// ResetKeyring forgets the cached passphrase and keys, so tests
+// can change the passphrase
+func ResetKeyring() {
+	keyring.pass = nil
+	keyring.keys = nil
+}
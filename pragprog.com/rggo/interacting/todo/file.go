@@ -0,0 +1,113 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockTimeout is how long Lock waits for another process to release the list
+var LockTimeout = 10 * time.Second
+
+// errLocked is returned by lockFile when another process holds the lock
+var errLocked = errors.New("file is locked")
+
+// FileLock is an advisory lock that serializes access to a list file
+// across processes. The lock is released when the process exits.
+type FileLock struct {
+	f *os.File
+}
+
+// Lock acquires an exclusive advisory lock for the provided list file
+// name. Hold the lock for the whole load, modify and save cycle to
+// prevent concurrent processes from losing each other's updates.
+func Lock(filename string) (*FileLock, error) {
+	f, err := os.OpenFile(filename+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		err := lockFile(f)
+		if err == nil {
+			return &FileLock{f: f}, nil
+		}
+
+		if !errors.Is(err, errLocked) || time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("Cannot lock %s: %w", filename, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Unlock releases the lock
+func (fl *FileLock) Unlock() error {
+	if err := unlockFile(fl.f); err != nil {
+		fl.f.Close()
+		return err
+	}
+
+	return fl.f.Close()
+}
+
+// writeFile atomically replaces filename with data. It writes to a
+// temporary file in the same directory, flushes it to disk and renames
+// it over the original so readers never observe a partial write.
+// New files get perm, existing ones keep their permissions, and a
+// symlink is followed so the file it points to is replaced instead.
+func writeFile(filename string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+
+	if fi, err := os.Stat(filename); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	// Removing the temporary file fails harmlessly after the rename
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+
+	// Persist the rename itself. Not every platform supports
+	// syncing directories so errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package todo_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestLockConcurrentSaves tests that locked load, modify and save
+// cycles running concurrently don't lose updates
+func TestLockConcurrentSaves(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	workers := 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			lock, err := todo.Lock(filename)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer lock.Unlock()
+
+			l := todo.List{}
+			if err := l.Get(filename); err != nil {
+				errs <- err
+				return
+			}
+			l.Add("New Task")
+			errs <- l.Save(filename)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := todo.List{}
+	if err := l.Get(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != workers {
+		t.Errorf("Expected %d items, got %d instead.", workers, len(l))
+	}
+
+	// Only the list and its lock file should be left behind
+	files, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected %d files, got %d instead.", 2, len(files))
+	}
+}
+
+// TestSaveKeepsFile tests saving keeps the permissions of the list
+// file and writes through a symlink to it
+func TestSaveKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "todo.json")
+	link := filepath.Join(dir, "link.json")
+
+	l := todo.List{}
+	l.Add("Task 1")
+	if err := l.Save(target); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(target, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("Symlinks not supported:", err)
+	}
+
+	l.Add("Task 2")
+	if err := l.Save(link); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected %s to stay a symlink", link)
+	}
+
+	if fi, err = os.Stat(target); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions %v, got %v instead.", os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	l2 := todo.List{}
+	if err := l2.Get(target); err != nil {
+		t.Fatal(err)
+	}
+	if len(l2) != 2 {
+		t.Errorf("Expected %d items, got %d instead.", 2, len(l2))
+	}
+}
@@ -0,0 +1,457 @@
+package todo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// Row is an item of the list, or one of its subtasks,
+// as shown by a Formatter
+type Row struct {
+	Address      string
+	Depth        int
+	Task         string
+	Done         bool
+	CreatedAt    time.Time
+	CompletedAt  time.Time
+	Due          time.Time
+	Recur        string
+	Priority     string
+	Notes        string
+	Subtasks     int
+	SubtasksDone int
+	Spent        time.Duration
+	Running      bool
+	BlockedBy    []string // addresses of the pending items it depends on
+	Remind       time.Time
+}
+
+// Default layouts of the times and dates shown by the text and
+// table formatters. The JSON and CSV formatters keep fixed layouts
+const (
+	DefaultTimeLayout = "2006-01-02 15:04"
+	DefaultDateLayout = "2006-01-02"
+)
+
+// Sort orders of the rows of a list, applied to the items and to
+// the subtasks of each item. Rows keep their address
+const (
+	SortCreated  = "created"
+	SortDue      = "due"
+	SortPriority = "priority"
+	SortTask     = "task"
+)
+
+// CheckSortOrder returns an error if order is not a sort order
+func CheckSortOrder(order string) error {
+	switch order {
+	case SortCreated, SortDue, SortPriority, SortTask:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid sort order %q: use created, due, priority or task", order)
+}
+
+// Rows returns the items of the list followed by their subtasks,
+// in the order they were added. When pending is set, completed
+// items and their subtasks are skipped
+func (l *List) Rows(pending bool) []Row {
+	return l.SortedRows(pending, SortCreated)
+}
+
+// SortedRows returns the rows like Rows, with the items and the
+// subtasks of each item in the given sort order
+func (l *List) SortedRows(pending bool, order string) []Row {
+	nodes := l.nodes()
+	blockers := func(t item) []string { return l.blockers(t, nodes) }
+
+	return l.rows("", 0, pending, order, time.Now(), blockers, nil)
+}
+
+// rows appends the rows of the list to rs. parent is the address
+// of the item owning the list and depth its nesting level
+func (l *List) rows(parent string, depth int, pending bool, order string, now time.Time,
+	blockers func(item) []string, rs []Row) []Row {
+	for _, k := range l.order(order) {
+		t := (*l)[k]
+		if pending && t.Done {
+			continue
+		}
+
+		addr := fmt.Sprintf("%s%d", parent, k+1)
+		rs = append(rs, Row{
+			Address:      addr,
+			Depth:        depth,
+			Task:         t.Task,
+			Done:         t.Done,
+			CreatedAt:    t.CreatedAt,
+			CompletedAt:  t.CompletedAt,
+			Due:          t.Due,
+			Recur:        t.Recur,
+			Priority:     t.Priority,
+			Notes:        t.Notes,
+			Subtasks:     len(t.Children),
+			SubtasksDone: len(t.Children) - t.Children.pending(),
+			Spent:        t.Spent(now),
+			Running:      t.Running(),
+			BlockedBy:    blockers(t),
+			Remind:       t.Remind,
+		})
+
+		rs = t.Children.rows(addr+".", depth+1, pending, order, now, blockers, rs)
+	}
+
+	return rs
+}
+
+// order returns the indexes of the items in the sort order. Items
+// without a due date or a priority come last, and ties keep the
+// order of the list
+func (l *List) order(order string) []int {
+	idx := make([]int, len(*l))
+	for k := range idx {
+		idx[k] = k
+	}
+
+	var less func(a, b item) bool
+	switch order {
+	case SortDue:
+		less = func(a, b item) bool {
+			return !a.Due.IsZero() && (b.Due.IsZero() || a.Due.Before(b.Due))
+		}
+	case SortPriority:
+		less = func(a, b item) bool {
+			return a.Priority != "" && (b.Priority == "" || a.Priority < b.Priority)
+		}
+	case SortTask:
+		less = func(a, b item) bool { return strings.ToLower(a.Task) < strings.ToLower(b.Task) }
+	default:
+		return idx
+	}
+
+	sort.SliceStable(idx, func(a, b int) bool { return less((*l)[idx[a]], (*l)[idx[b]]) })
+	return idx
+}
+
+// Formatter renders rows of a list
+type Formatter interface {
+	Format(w io.Writer, rows []Row) error
+}
+
+// NewFormatter returns the built-in formatter with the given
+// name: plain, table, json or csv
+func NewFormatter(name string) (Formatter, error) {
+	switch name {
+	case "plain", "text":
+		return PlainFormatter{}, nil
+	case "table":
+		return TableFormatter{}, nil
+	case "json":
+		return JSONFormatter{}, nil
+	case "csv":
+		return CSVFormatter{}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown output format %q", name)
+}
+
+// PlainFormatter prints one line per row with subtasks indented
+// below their parent. Verbose adds the creation date and schedule,
+// shown with the layouts given or the default ones. Color shows
+// completed rows faint, and pending ones due today in yellow and
+// overdue in red, with ANSI escape codes
+type PlainFormatter struct {
+	Verbose    bool
+	Color      bool
+	TimeLayout string
+	DateLayout string
+}
+
+// ANSI escape codes used by PlainFormatter
+const (
+	ansiFaint  = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// Format implements the Formatter interface
+func (f PlainFormatter) Format(w io.Writer, rows []Row) error {
+	today := day(time.Now())
+
+	for _, r := range rows {
+		prefix := "  "
+		if r.Done {
+			prefix = "X "
+		}
+
+		line := fmt.Sprintf("%s%s%s: %s%s%s%s", strings.Repeat("  ", r.Depth), prefix, r.Address, r.Task,
+			r.progress(), r.timer(), r.blocked())
+		if f.Verbose {
+			timeLayout := or(f.TimeLayout, DefaultTimeLayout)
+			line += "\t" + r.CreatedAt.Format(timeLayout) + r.schedule(timeLayout, or(f.DateLayout, DefaultDateLayout))
+		}
+
+		if c := r.color(today); f.Color && c != "" {
+			line = c + line + ansiReset
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TableFormatter prints the rows as a table with aligned columns
+// and their dates, shown with the layouts given or the default ones
+type TableFormatter struct {
+	TimeLayout string
+	DateLayout string
+}
+
+// Format implements the Formatter interface
+func (f TableFormatter) Format(w io.Writer, rows []Row) error {
+	timeLayout := or(f.TimeLayout, DefaultTimeLayout)
+	dateLayout := or(f.DateLayout, DefaultDateLayout)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tDONE\tTASK\tCREATED\tDUE\tCOMPLETED\tRECUR\tSPENT")
+
+	for _, r := range rows {
+		done := ""
+		if r.Done {
+			done = "X"
+		}
+
+		spent := "-"
+		if r.Spent > 0 {
+			spent = formatClock(r.Spent)
+		}
+		if r.Running {
+			spent += " *"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s%s%s\t%s\t%s\t%s\t%s\t%s\n", r.Address, done,
+			strings.Repeat("  ", r.Depth), r.Task, r.progress(),
+			formatTime(r.CreatedAt, timeLayout, "-"),
+			formatTime(r.Due, dateLayout, "-"),
+			formatTime(r.CompletedAt, timeLayout, "-"),
+			or(r.Recur, "-"), spent)
+	}
+
+	return tw.Flush()
+}
+
+// jsonRow is the JSON representation of a row. Unset dates
+// are left out
+type jsonRow struct {
+	Address     string     `json:"address"`
+	Task        string     `json:"task"`
+	Done        bool       `json:"done"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Due         string     `json:"due,omitempty"`
+	Recur       string     `json:"recur,omitempty"`
+	Priority    string     `json:"priority,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	Subtasks    int        `json:"subtasks,omitempty"`
+	Spent       int64      `json:"spent_ns,omitempty"`
+	Running     bool       `json:"running,omitempty"`
+	BlockedBy   []string   `json:"blocked_by,omitempty"`
+	Remind      *time.Time `json:"remind,omitempty"`
+}
+
+// JSONFormatter prints the rows as a JSON array
+type JSONFormatter struct{}
+
+// Format implements the Formatter interface
+func (JSONFormatter) Format(w io.Writer, rows []Row) error {
+	js := make([]jsonRow, 0, len(rows))
+
+	for _, r := range rows {
+		jr := jsonRow{
+			Address:   r.Address,
+			Task:      r.Task,
+			Done:      r.Done,
+			CreatedAt: r.CreatedAt,
+			Due:       formatTime(r.Due, "2006-01-02", ""),
+			Recur:     r.Recur,
+			Priority:  r.Priority,
+			Notes:     r.Notes,
+			Subtasks:  r.Subtasks,
+			Spent:     int64(r.Spent),
+			Running:   r.Running,
+			BlockedBy: r.BlockedBy,
+		}
+		if r.Done {
+			jr.CompletedAt = &r.CompletedAt
+		}
+		if !r.Remind.IsZero() {
+			jr.Remind = &r.Remind
+		}
+
+		js = append(js, jr)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(js)
+}
+
+// CSVFormatter prints the rows as CSV records with a header
+type CSVFormatter struct{}
+
+// Format implements the Formatter interface
+func (CSVFormatter) Format(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"address", "task", "done", "created_at", "completed_at",
+		"due", "recur", "priority", "notes", "spent_minutes"})
+
+	for _, r := range rows {
+		cw.Write([]string{
+			r.Address,
+			r.Task,
+			strconv.FormatBool(r.Done),
+			formatTime(r.CreatedAt, time.RFC3339, ""),
+			formatTime(r.CompletedAt, time.RFC3339, ""),
+			formatTime(r.Due, "2006-01-02", ""),
+			r.Recur,
+			r.Priority,
+			r.Notes,
+			strconv.Itoa(int(r.Spent.Minutes())),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// TemplateFormatter prints the rows with a user provided
+// text/template. The template is executed once with the
+// slice of rows, so it can range over them
+type TemplateFormatter struct {
+	tmpl *template.Template
+}
+
+// templateFuncs are the functions available to templates
+var templateFuncs = template.FuncMap{
+	// date formats a time with a layout, or returns "" if it's not set
+	"date": func(layout string, t time.Time) string {
+		return formatTime(t, layout, "")
+	},
+	// indent returns two spaces per nesting level
+	"indent": func(depth int) string {
+		return strings.Repeat("  ", depth)
+	},
+}
+
+// NewTemplateFormatter parses the template in the file
+func NewTemplateFormatter(filename string) (*TemplateFormatter, error) {
+	text, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filename).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TemplateFormatter{tmpl: tmpl}, nil
+}
+
+// Format implements the Formatter interface
+func (f *TemplateFormatter) Format(w io.Writer, rows []Row) error {
+	return f.tmpl.Execute(w, rows)
+}
+
+// progress returns the number of completed subtasks out of the
+// total, or an empty string for rows without subtasks
+func (r Row) progress() string {
+	if r.Subtasks == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" (%d/%d)", r.SubtasksDone, r.Subtasks)
+}
+
+// timer returns the time spent on a row with a running session
+func (r Row) timer() string {
+	if !r.Running {
+		return ""
+	}
+
+	return fmt.Sprintf(" [running, %s]", formatClock(r.Spent))
+}
+
+// blocked returns the items blocking a pending row
+func (r Row) blocked() string {
+	if r.Done || len(r.BlockedBy) == 0 {
+		return ""
+	}
+
+	return " [blocked by " + strings.Join(r.BlockedBy, ", ") + "]"
+}
+
+// color returns the ANSI escape code the row is shown with,
+// or empty if it's shown in the default color
+func (r Row) color(today time.Time) string {
+	switch {
+	case r.Done:
+		return ansiFaint
+	case r.Due.IsZero():
+		return ""
+	case r.Due.Before(today):
+		return ansiRed
+	case !r.Due.After(today):
+		return ansiYellow
+	}
+
+	return ""
+}
+
+// schedule returns the due date and recurrence rule of the row
+// for verbose listings
+func (r Row) schedule(timeLayout, dateLayout string) string {
+	s := ""
+	if !r.Due.IsZero() {
+		s += "\tdue " + r.Due.Format(dateLayout)
+	}
+	if r.Recur != "" {
+		s += " (" + r.Recur + ")"
+	}
+	if !r.Remind.IsZero() {
+		s += "\tremind " + r.Remind.Format(timeLayout)
+	}
+
+	return s
+}
+
+// formatTime formats t with the layout, or returns empty if t is not set
+func formatTime(t time.Time, layout, empty string) string {
+	if t.IsZero() {
+		return empty
+	}
+
+	return t.Format(layout)
+}
+
+// or returns s, or def if s is empty
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+
+	return s
+}
@@ -0,0 +1,250 @@
+package todo_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestRows tests the rows of the list include subtasks with their
+// addresses and nesting level
+func TestRows(t *testing.T) {
+	l := newNestedList(t)
+	l.Complete(2)
+
+	rows := l.Rows(false)
+
+	var addrs []string
+	for _, r := range rows {
+		addrs = append(addrs, r.Address)
+	}
+	if got := strings.Join(addrs, " "); got != "1 1.1 1.2 1.2.1 2" {
+		t.Errorf("Expected addresses %q, got %q instead.", "1 1.1 1.2 1.2.1 2", got)
+	}
+
+	if rows[3].Depth != 2 || rows[0].Subtasks != 2 {
+		t.Errorf("Unexpected rows %+v", rows)
+	}
+
+	if n := len(l.Rows(true)); n != 4 {
+		t.Errorf("Expected %d pending rows, got %d instead.", 4, n)
+	}
+}
+
+// TestFormatters tests the built-in formatters
+func TestFormatters(t *testing.T) {
+	l := todo.List{}
+	l.Add("Rotate certificates", todo.WithDue(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)),
+		todo.WithRecurrence("monthly"))
+	l.Add("Say \"hello\", world")
+	l.Complete(2)
+
+	t.Run("Plain", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := (todo.PlainFormatter{}).Format(&b, l.Rows(false)); err != nil {
+			t.Fatal(err)
+		}
+
+		if b.String() != l.String() {
+			t.Errorf("Expected %q, got %q instead.", l.String(), b.String())
+		}
+	})
+
+	t.Run("Table", func(t *testing.T) {
+		f, err := todo.NewFormatter("table")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		if err := f.Format(&b, l.Rows(false)); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected %d lines, got %q instead.", 3, b.String())
+		}
+
+		// Columns are aligned
+		col := strings.Index(lines[0], "CREATED")
+		if !strings.HasPrefix(lines[1][col:], l[0].CreatedAt.Format("2006-01-02")) {
+			t.Errorf("Expected creation date aligned in %q", lines[1])
+		}
+		if !strings.Contains(lines[1], "2024-05-01") || !strings.Contains(lines[1], "monthly") {
+			t.Errorf("Expected due date and recurrence in %q", lines[1])
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		f, err := todo.NewFormatter("json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		if err := f.Format(&b, l.Rows(false)); err != nil {
+			t.Fatal(err)
+		}
+
+		var rows []map[string]any
+		if err := json.Unmarshal(b.Bytes(), &rows); err != nil {
+			t.Fatal(err)
+		}
+
+		if rows[0]["due"] != "2024-05-01" || rows[0]["completed_at"] != nil {
+			t.Errorf("Unexpected row %v", rows[0])
+		}
+		if rows[1]["done"] != true || rows[1]["completed_at"] == nil {
+			t.Errorf("Unexpected row %v", rows[1])
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		f, err := todo.NewFormatter("csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		if err := f.Format(&b, l.Rows(false)); err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := csv.NewReader(&b).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 3 || records[0][1] != "task" {
+			t.Fatalf("Unexpected records %q", records)
+		}
+		if records[2][1] != l[1].Task || records[2][2] != "true" {
+			t.Errorf("Unexpected record %q", records[2])
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		if _, err := todo.NewFormatter("xml"); err == nil {
+			t.Error("Expected error for unknown format")
+		}
+	})
+}
+
+// TestTemplateFormatter tests listing with a user provided template
+func TestTemplateFormatter(t *testing.T) {
+	l := newNestedList(t)
+
+	tf := filepath.Join(t.TempDir(), "list.tmpl")
+	tmpl := `{{range .}}{{indent .Depth}}[{{if .Done}}x{{else}} {{end}}] {{.Task}}{{with date "Jan 2" .Due}} ({{.}}){{end}}
+{{end}}`
+	if err := os.WriteFile(tf, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Edit(2, "Other task", todo.WithDue(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)))
+	l.Complete(2)
+
+	f, err := todo.NewTemplateFormatter(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.Format(&b, l.Rows(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[ ] Release\n  [ ] Write docs\n  [ ] Tag version\n    [ ] Sign tag\n[x] Other task (May 1)\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, b.String())
+	}
+}
+
+// TestSortOrder tests sorting the rows keeps their addresses
+// and keeps subtasks under their parent
+func TestSortOrder(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Water plants")
+	l.Add("Pay rent", todo.WithDue(day.AddDate(0, 0, 3)))
+	l.Add("File taxes (A)", todo.WithDue(day))
+	l.AddAt([]int{2}, "Transfer money")
+	l.AddAt([]int{2}, "Check balance")
+	l[2].Priority = "A"
+
+	testCases := []struct {
+		order    string
+		expected string
+	}{
+		{todo.SortCreated, "1 2 2.1 2.2 3"},
+		{todo.SortDue, "3 2 2.1 2.2 1"},
+		{todo.SortPriority, "3 1 2 2.1 2.2"},
+		{todo.SortTask, "3 2 2.2 2.1 1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.order, func(t *testing.T) {
+			if err := todo.CheckSortOrder(tc.order); err != nil {
+				t.Fatal(err)
+			}
+
+			var addrs []string
+			for _, r := range l.SortedRows(false, tc.order) {
+				addrs = append(addrs, r.Address)
+			}
+			if got := strings.Join(addrs, " "); got != tc.expected {
+				t.Errorf("Expected addresses %q, got %q instead.", tc.expected, got)
+			}
+		})
+	}
+
+	if err := todo.CheckSortOrder("random"); err == nil {
+		t.Error("Expected error for an invalid sort order")
+	}
+}
+
+// TestLayoutsAndColor tests the configurable date layouts and
+// the colors of the plain formatter
+func TestLayoutsAndColor(t *testing.T) {
+	today := time.Now()
+	due := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Call plumber", todo.WithDue(due))
+	l.Add("Pay rent", todo.WithDue(due.AddDate(0, 0, -1)))
+	l.Add("Read book")
+
+	var b bytes.Buffer
+	f := todo.PlainFormatter{Verbose: true, TimeLayout: "02/01 15:04", DateLayout: "02/01/2006"}
+	if err := f.Format(&b, l.Rows(false)); err != nil {
+		t.Fatal(err)
+	}
+	if v := b.String(); !strings.Contains(v, "\tdue "+due.Format("02/01/2006")) ||
+		!strings.Contains(v, "\t"+l[0].CreatedAt.Format("02/01 15:04")) {
+		t.Errorf("Expected custom layouts, got %q instead.", v)
+	}
+
+	// The default layouts are kept elsewhere
+	if v := l.Verbose(); !strings.Contains(v, "\tdue "+due.Format(todo.DefaultDateLayout)) {
+		t.Errorf("Expected default layouts, got %q instead.", v)
+	}
+
+	b.Reset()
+	if err := (todo.PlainFormatter{Color: true}).Format(&b, l.Rows(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\x1b[33m  1: Call plumber\x1b[0m\n\x1b[31m  2: Pay rent\x1b[0m\n  3: Read book\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, b.String())
+	}
+}
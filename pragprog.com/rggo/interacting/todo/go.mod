@@ -1,3 +1,10 @@
 module pragprog.com/rggo/interacting/todo
 
 go 1.22.5
+
+require (
+	golang.org/x/crypto v0.33.0
+	golang.org/x/term v0.29.0
+)
+
+require golang.org/x/sys v0.30.0 // indirect
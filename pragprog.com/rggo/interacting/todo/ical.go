@@ -0,0 +1,338 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// icsUID is appended to item IDs to build the UIDs of the
+	// calendar components, keeping them stable across exports
+	icsUID = "@todo"
+
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+	icsUTC      = "20060102T150405Z"
+)
+
+// icsDays maps weekdays to their iCalendar abbreviations
+var icsDays = map[time.Weekday]string{
+	time.Sunday:    "SU",
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+}
+
+// ExportICS writes the items with a due date, including subtasks,
+// to w as an iCalendar file of VTODO components. When events is
+// true, an all-day VEVENT on the due date is added for each item
+func (l *List) ExportICS(w io.Writer, events bool) error {
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//pragprog.com//todo//EN")
+	iw.line("CALSCALE:GREGORIAN")
+
+	l.walk(nil, func(path []int, t item) {
+		if t.Due.IsZero() {
+			return
+		}
+
+		iw.line("BEGIN:VTODO")
+		iw.line("UID:" + t.ID + icsUID)
+		iw.line("DTSTAMP:" + t.CreatedAt.UTC().Format(icsUTC))
+		iw.line("CREATED:" + t.CreatedAt.UTC().Format(icsUTC))
+		iw.line("SUMMARY:" + icsEscape(t.Task))
+		iw.line("DUE;VALUE=DATE:" + t.Due.Format(icsDate))
+
+		if t.Done {
+			iw.line("STATUS:COMPLETED")
+			iw.line("COMPLETED:" + t.CompletedAt.UTC().Format(icsUTC))
+		} else {
+			iw.line("STATUS:NEEDS-ACTION")
+		}
+
+		if p := icsPriority(t.Priority); p != "" {
+			iw.line("PRIORITY:" + p)
+		}
+		if t.Notes != "" {
+			iw.line("DESCRIPTION:" + icsEscape(t.Notes))
+		}
+		if rule := icsRule(t.Recur); rule != "" {
+			iw.line("RRULE:" + rule)
+		}
+		if tags := t.Tags(); len(tags) > 0 {
+			cats := make([]string, len(tags))
+			for k, tag := range tags {
+				cats[k] = icsEscape(tag)
+			}
+			iw.line("CATEGORIES:" + strings.Join(cats, ","))
+		}
+		iw.line("END:VTODO")
+
+		if !events {
+			return
+		}
+
+		iw.line("BEGIN:VEVENT")
+		iw.line("UID:" + t.ID + "-event" + icsUID)
+		iw.line("DTSTAMP:" + t.CreatedAt.UTC().Format(icsUTC))
+		iw.line("SUMMARY:" + icsEscape(t.Task))
+		iw.line("DTSTART;VALUE=DATE:" + t.Due.Format(icsDate))
+		iw.line("DTEND;VALUE=DATE:" + t.Due.AddDate(0, 0, 1).Format(icsDate))
+		iw.line("TRANSP:TRANSPARENT")
+		iw.line("END:VEVENT")
+	})
+
+	iw.line("END:VCALENDAR")
+
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// ImportICS reads the VTODO components of an iCalendar file.
+// Other components are ignored. Items exported by ExportICS get
+// their IDs back from their UIDs, and other UIDs are hashed into
+// IDs, so importing a file again gives the same items
+func ImportICS(r io.Reader) (List, error) {
+	l := List{}
+
+	lines, err := icsLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// nested counts the components open inside the VTODO, such as
+	// alarms, whose properties aren't the item's
+	var t *item
+	nested := 0
+	for _, line := range lines {
+		name, params, value := icsProperty(line)
+
+		switch {
+		case name == "BEGIN" && value == "VTODO":
+			t = &item{ID: newID(), CreatedAt: time.Now()}
+			nested = 0
+
+		case t == nil:
+			// Outside of a VTODO
+
+		case name == "BEGIN":
+			nested++
+		case nested > 0:
+			if name == "END" {
+				nested--
+			}
+
+		case name == "END" && value == "VTODO":
+			if t.Task != "" {
+				l = append(l, *t)
+			}
+			t = nil
+
+		case name == "UID":
+			t.ID = icsID(value)
+		case name == "SUMMARY":
+			t.Task = icsUnescape(value)
+		case name == "DESCRIPTION":
+			t.Notes = icsUnescape(value)
+		case name == "STATUS":
+			t.Done = value == "COMPLETED"
+		case name == "PRIORITY":
+			t.Priority = txtPriority(value)
+
+		case name == "DUE", name == "CREATED", name == "COMPLETED":
+			d, err := icsTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s %q: %w", name, value, err)
+			}
+
+			switch name {
+			case "DUE":
+				// Due dates are local days, whatever the zone of the time
+				t.Due = day(d.In(time.Local))
+			case "CREATED":
+				t.CreatedAt = d
+			case "COMPLETED":
+				t.Done, t.CompletedAt = true, d
+			}
+		}
+	}
+
+	// Completed items without a completion time were completed on import
+	for k := range l {
+		if l[k].Done && l[k].CompletedAt.IsZero() {
+			l[k].CompletedAt = time.Now()
+		}
+	}
+
+	return l, nil
+}
+
+// icsID returns the ID of the item with the given UID
+func icsID(uid string) string {
+	if id, ok := strings.CutSuffix(uid, icsUID); ok && id != "" {
+		return id
+	}
+
+	return contentID("", uid)
+}
+
+// icsWriter writes content lines, folding them at 75 octets
+// as required by iCalendar, and keeps the first error
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line
+func (iw *icsWriter) line(s string) {
+	if iw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range s {
+		size := len(string(c))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(c)
+		n += size
+	}
+	b.WriteString("\r\n")
+
+	_, iw.err = iw.w.WriteString(b.String())
+}
+
+// icsLines reads the content lines of an iCalendar file, unfolding
+// lines continued with leading whitespace
+func icsLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, s.Err()
+}
+
+// icsProperty splits a content line into its upper case name,
+// its parameters and its value
+func icsProperty(line string) (string, map[string]string, string) {
+	head, value, _ := strings.Cut(line, ":")
+	fields := strings.Split(head, ";")
+
+	params := map[string]string{}
+	for _, p := range fields[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+
+	return strings.ToUpper(fields[0]), params, value
+}
+
+// icsTime parses a date or date-time value, in UTC, in the time
+// zone of its TZID parameter or otherwise in local time
+func icsTime(params map[string]string, value string) (time.Time, error) {
+	loc := time.Local
+	if tz := params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+
+	switch {
+	case len(value) == len(icsDate):
+		return time.ParseInLocation(icsDate, value, time.Local)
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse(icsUTC, value)
+	}
+
+	return time.ParseInLocation(icsDateTime, value, loc)
+}
+
+// icsRule converts a recurrence rule into an iCalendar RRULE.
+// Rules counting from the completion date can't be expressed
+func icsRule(rule string) string {
+	if rule == "" {
+		return ""
+	}
+
+	r, err := ParseRecurrence(rule)
+	if err != nil {
+		return ""
+	}
+
+	switch r.Kind {
+	case RecurDaily:
+		return "FREQ=DAILY"
+	case RecurWeekly:
+		if len(r.Weekdays) == 0 {
+			return "FREQ=WEEKLY"
+		}
+		days := make([]string, len(r.Weekdays))
+		for k, d := range r.Weekdays {
+			days[k] = icsDays[d]
+		}
+		return "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+	case RecurMonthly:
+		if r.Day == 0 {
+			return "FREQ=MONTHLY"
+		}
+		return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%d", r.Day)
+	}
+
+	return ""
+}
+
+// icsPriority converts a todo.txt priority, A being the highest,
+// into an iCalendar priority from 1 to 9
+func icsPriority(p string) string {
+	if !isPriority(p) {
+		return ""
+	}
+
+	return fmt.Sprint(min(int(p[0]-'A')+1, 9))
+}
+
+// txtPriority converts an iCalendar priority into a todo.txt one.
+// 0 means undefined
+func txtPriority(p string) string {
+	if len(p) != 1 || p[0] < '1' || p[0] > '9' {
+		return ""
+	}
+
+	return string(rune('A' + p[0] - '1'))
+}
+
+// icsEscape escapes a text value
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsUnescape unescapes a text value
+func icsUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
@@ -0,0 +1,169 @@
+package todo_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestExportICS tests exporting the items with a due date as VTODOs
+func TestExportICS(t *testing.T) {
+	due := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Rotate certificates, staging; prod +ops", todo.WithDue(due), todo.WithRecurrence("weekly=mon,thu"))
+	l.Add("No due date")
+	l.Add("Renew domain", todo.WithDue(due.AddDate(0, 0, 1)), todo.WithNotes("Registrar: Gandi"))
+	l.Complete(3)
+	l.Add(strings.Repeat("Long task description ", 5), todo.WithDue(due))
+
+	var b bytes.Buffer
+	if err := l.ExportICS(&b, true); err != nil {
+		t.Fatal(err)
+	}
+	ics := b.String()
+
+	for _, exp := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"UID:" + l[0].ID + "@todo\r\n",
+		`SUMMARY:Rotate certificates\, staging\; prod +ops` + "\r\n",
+		"DUE;VALUE=DATE:20240501\r\nSTATUS:NEEDS-ACTION\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,TH\r\n",
+		"CATEGORIES:+ops\r\n",
+		"STATUS:COMPLETED\r\nCOMPLETED:" + l[2].CompletedAt.UTC().Format("20060102T150405Z"),
+		"DESCRIPTION:Registrar: Gandi\r\n",
+		"DTSTART;VALUE=DATE:20240502\r\nDTEND;VALUE=DATE:20240503\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, exp) {
+			t.Errorf("Expected %q in export:\n%s", exp, ics)
+		}
+	}
+
+	if strings.Contains(ics, "No due date") {
+		t.Errorf("Expected items without due date to be left out")
+	}
+
+	if n := strings.Count(ics, "BEGIN:VTODO"); n != 3 {
+		t.Errorf("Expected %d VTODOs, got %d instead.", 3, n)
+	}
+
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected lines folded at 75 octets, got %q", line)
+		}
+	}
+
+	// Importing the export gives the same items back
+	items, err := todo.ImportICS(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 3 || items[0].Task != l[0].Task || items[2].Task != l[3].Task {
+		t.Fatalf("Unexpected items %v", items)
+	}
+	if !items[1].Done || items[1].Notes != l[2].Notes || !items[1].Due.Equal(l[2].Due) {
+		t.Errorf("Expected %v, got %v instead.", l[2], items[1])
+	}
+	if items[0].ID != l[0].ID || items[2].ID != l[3].ID {
+		t.Errorf("Expected imported items to keep their IDs, got %q and %q instead.", items[0].ID, items[2].ID)
+	}
+
+	// Importing them into the list again skips them
+	j := todo.NewJournal(filepath.Join(t.TempDir(), "todo.json"))
+	j.Import(&l, items)
+	if len(l) != 4 {
+		t.Errorf("Expected %d items, got %d instead.", 4, len(l))
+	}
+}
+
+// TestImportICS tests importing VTODOs written by calendar apps
+func TestImportICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//Example Corp.//CalDAV Client//EN",
+		"BEGIN:VEVENT",
+		"SUMMARY:Team meeting",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:20240501T120000-123@example.com",
+		"SUMMARY:Submit quarterly",
+		"  report",
+		"DUE;TZID=America/New_York:20240510T170000",
+		"PRIORITY:2",
+		"DESCRIPTION:Send it to finance",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Reminder",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"SUMMARY:Pay invoice",
+		"STATUS:COMPLETED",
+		"COMPLETED:20240502T101500Z",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	l, err := todo.ImportICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 2 {
+		t.Fatalf("Expected %d items, got %v instead.", 2, l)
+	}
+
+	if l[0].Task != "Submit quarterly report" || l[0].Priority != "B" || l[0].Due.Day() != 10 ||
+		l[0].Notes != "Send it to finance" {
+		t.Errorf("Unexpected item %+v", l[0])
+	}
+
+	completed := time.Date(2024, 5, 2, 10, 15, 0, 0, time.UTC)
+	if !l[1].Done || !l[1].CompletedAt.Equal(completed) {
+		t.Errorf("Unexpected item %+v", l[1])
+	}
+
+	// Items with a UID get the same ID every time
+	l2, err := todo.ImportICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l2[0].ID != l[0].ID || l2[1].ID == l[1].ID {
+		t.Errorf("Expected the same ID only for the item with a UID, got %v and %v", l, l2)
+	}
+}
+
+// TestImportICSDueUTC tests a due time in UTC is due on its local day
+func TestImportICSDueUTC(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	local := time.Local
+	time.Local = ny
+	defer func() { time.Local = local }()
+
+	ics := "BEGIN:VTODO\r\nSUMMARY:Call the bank\r\nDUE:20240301T170000Z\r\nEND:VTODO\r\n"
+
+	l, err := todo.ImportICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2024, 3, 1, 0, 0, 0, 0, ny)
+	if len(l) != 1 || !l[0].Due.Equal(expected) {
+		t.Fatalf("Expected due %s, got %v instead.", expected, l)
+	}
+
+	if st := l.Status(time.Date(2024, 3, 1, 18, 0, 0, 0, ny)); st.Overdue != 0 || st.DueToday != 1 {
+		t.Errorf("Expected item due today, got %s instead.", st)
+	}
+}
@@ -0,0 +1,539 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// Operations recorded in the journal
+const (
+	OpAdd      = "add"
+	OpComplete = "complete"
+	OpReopen   = "reopen"
+	OpDelete   = "delete"
+	OpEdit     = "edit"
+	OpImport   = "import"
+	OpUndo     = "undo"
+	OpRedo     = "redo"
+	OpStart    = "start"
+	OpStop     = "stop"
+	OpDepend   = "depend"
+	OpUndepend = "undepend"
+	OpArchive  = "archive"
+	OpRestore  = "restore"
+)
+
+var (
+	// ErrNothingToUndo is returned by Undo when no operation can be reversed
+	ErrNothingToUndo = errors.New("Nothing to undo")
+	// ErrNothingToRedo is returned by Redo when no operation can be replayed
+	ErrNothingToRedo = errors.New("Nothing to redo")
+	// ErrListChanged is returned when the list no longer matches the
+	// journal, so an operation cannot be safely undone or redone
+	ErrListChanged = errors.New("List changed outside of the journal")
+)
+
+// Change records the state of a single position of the List
+// before and after an operation. Before is nil for inserted items
+// and After is nil for removed items.
+type Change struct {
+	Index  int
+	Before *item `json:",omitempty"`
+	After  *item `json:",omitempty"`
+}
+
+// Entry is a single record of the journal
+type Entry struct {
+	Time    time.Time
+	User    string
+	Op      string
+	Ref     int      `json:",omitempty"`
+	Changes []Change `json:",omitempty"`
+}
+
+// String prints out a one line summary of the entry
+func (e Entry) String() string {
+	return e.Describe(DefaultTimeLayout)
+}
+
+// Describe prints out a one line summary of the entry
+// with its time in the given layout
+func (e Entry) Describe(layout string) string {
+	var desc []string
+
+	switch e.Op {
+	case OpUndo, OpRedo:
+		desc = append(desc, fmt.Sprintf("#%d", e.Ref))
+	default:
+		for _, c := range e.Changes {
+			t := c.After
+			if t == nil {
+				t = c.Before
+			}
+			desc = append(desc, fmt.Sprintf("%d: %s", c.Index+1, t.Task))
+		}
+	}
+
+	return fmt.Sprintf("%s %s %s %s", e.Time.Format(layout),
+		e.User, e.Op, strings.Join(desc, ", "))
+}
+
+// Journal is an append-only log of the operations applied to a List.
+// Operations are buffered until Commit is called, so callers can
+// save the List first and only journal operations that were persisted.
+type Journal struct {
+	// Encrypt encrypts the entries committed, usually
+	// along with the list file
+	Encrypt bool
+
+	filename string
+	user     string
+	pending  []Entry
+	salt     []byte // salt of the encrypted entries
+}
+
+// JournalFile returns the name of the journal kept next to the list file
+func JournalFile(filename string) string {
+	return filename + ".journal"
+}
+
+// NewJournal returns the Journal for the provided list file name
+func NewJournal(filename string) *Journal {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	return &Journal{
+		filename: JournalFile(filename),
+		user:     name,
+	}
+}
+
+// Entries reads all the entries recorded in the journal
+func (j *Journal) Entries() ([]Entry, error) {
+	data, err := os.ReadFile(j.filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// A crash while committing can leave an incomplete last line
+	// which is ignored as that entry was never fully recorded
+	if k := bytes.LastIndexByte(data, '\n'); k != len(data)-1 {
+		data = data[:k+1]
+	}
+
+	var entries []Entry
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for s.Scan() {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+
+		line, err := openEntry(s.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("Invalid journal entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, s.Err()
+}
+
+// Commit appends the buffered entries to the journal file,
+// encrypted when Encrypt is set
+func (j *Journal) Commit() error {
+	if len(j.pending) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, e := range j.pending {
+		js, err := j.encode(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, js...), '\n')
+	}
+
+	f, err := os.OpenFile(j.filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Drop an incomplete last entry so the new ones start on a line
+	// of their own
+	size, err := completeSize(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := f.WriteAt(data, size); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	j.pending = nil
+
+	return f.Close()
+}
+
+// completeSize returns the size of the complete lines of f, reading
+// back from its end to the last newline
+func completeSize(f *os.File) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 4096)
+	for end := fi.Size(); end > 0; {
+		n := min(int64(len(buf)), end)
+		if _, err := f.ReadAt(buf[:n], end-n); err != nil {
+			return 0, err
+		}
+
+		if k := bytes.LastIndexByte(buf[:n], '\n'); k >= 0 {
+			return end - n + int64(k) + 1, nil
+		}
+		end -= n
+	}
+
+	return 0, nil
+}
+
+// Rewrite rewrites the whole journal, encrypting or decrypting its
+// entries to match the Encrypt setting
+func (j *Journal) Rewrite() error {
+	entries, err := j.Entries()
+	if err != nil || entries == nil {
+		return err
+	}
+
+	// Encrypted entries get a new salt
+	j.salt, err = newSalt()
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	for _, e := range entries {
+		js, err := j.encode(e)
+		if err != nil {
+			return err
+		}
+		b.Write(append(js, '\n'))
+	}
+
+	return writeFile(j.filename, b.Bytes(), 0644)
+}
+
+// encode encodes an entry as a line of the journal
+func (j *Journal) encode(e Entry) ([]byte, error) {
+	js, err := json.Marshal(e)
+	if err != nil || !j.Encrypt {
+		return js, err
+	}
+
+	return j.sealEntry(js)
+}
+
+// record buffers a new entry for the operation
+func (j *Journal) record(op string, ref int, changes ...Change) {
+	j.pending = append(j.pending, Entry{
+		Time:    time.Now(),
+		User:    j.user,
+		Op:      op,
+		Ref:     ref,
+		Changes: changes,
+	})
+}
+
+// Add adds a new item to the list and records the operation
+func (j *Journal) Add(l *List, task string, opts ...Option) {
+	l.Add(task, opts...)
+
+	i := len(*l) - 1
+	j.record(OpAdd, 0, Change{Index: i, After: copyItem((*l)[i])})
+}
+
+// Import appends the items to the list and records the operation.
+// Items with the ID of an item already in the list, such as tasks
+// exported and imported again, are skipped
+func (j *Journal) Import(l *List, items List) {
+	var changes []Change
+
+	ids := map[string]bool{}
+	l.walk(nil, func(path []int, t item) { ids[t.ID] = true })
+
+	for _, t := range items {
+		if ids[t.ID] {
+			continue
+		}
+		ids[t.ID] = true
+
+		*l = append(*l, t)
+		changes = append(changes, Change{Index: len(*l) - 1, After: copyItem(t)})
+	}
+
+	j.record(OpImport, 0, changes...)
+}
+
+// AddAt adds a new subtask to the item at parent and records the
+// operation. An empty parent adds the item to the list itself
+func (j *Journal) AddAt(l *List, parent []int, task string, opts ...Option) error {
+	if len(parent) == 0 {
+		j.Add(l, task, opts...)
+		return nil
+	}
+
+	return j.update(OpAdd, l, parent[0], func() error { return l.AddAt(parent, task, opts...) })
+}
+
+// Complete completes item i and records the operation
+func (j *Journal) Complete(l *List, i int) error {
+	return j.CompleteAt(l, []int{i}, true)
+}
+
+// CompleteAt completes the item at path, and its parents whose
+// subtasks are all done when parents is set, and records the operation
+func (j *Journal) CompleteAt(l *List, path []int, parents bool) error {
+	return j.updateAt(OpComplete, l, path, func() error { return l.CompleteAt(path, parents) })
+}
+
+// Reopen marks item i as pending again and records the operation
+func (j *Journal) Reopen(l *List, i int) error {
+	return j.ReopenAt(l, []int{i})
+}
+
+// ReopenAt marks the item at path as pending again and records the operation
+func (j *Journal) ReopenAt(l *List, path []int) error {
+	return j.updateAt(OpReopen, l, path, func() error { return l.ReopenAt(path) })
+}
+
+// Edit replaces the description of item i and records the operation
+func (j *Journal) Edit(l *List, i int, task string, opts ...Option) error {
+	return j.EditAt(l, []int{i}, task, opts...)
+}
+
+// EditAt replaces the description of the item at path and records the operation
+func (j *Journal) EditAt(l *List, path []int, task string, opts ...Option) error {
+	return j.updateAt(OpEdit, l, path, func() error { return l.EditAt(path, task, opts...) })
+}
+
+// Delete deletes item i from the list and records the operation
+func (j *Journal) Delete(l *List, i int) error {
+	return j.DeleteAt(l, []int{i})
+}
+
+// DeleteAt deletes the item at path and records the operation
+func (j *Journal) DeleteAt(l *List, path []int) error {
+	if len(path) != 1 {
+		return j.updateAt(OpDelete, l, path, func() error { return l.DeleteAt(path) })
+	}
+
+	i := path[0]
+	if i <= 0 || i > len(*l) {
+		return fmt.Errorf("Item %d does not exist", i)
+	}
+
+	before := copyItem((*l)[i-1])
+	if err := l.Delete(i); err != nil {
+		return err
+	}
+
+	j.record(OpDelete, 0, Change{Index: i - 1, Before: before})
+	return nil
+}
+
+// updateAt records changes to the item at path, or any of its
+// subtasks, as a change to the top level item holding them
+func (j *Journal) updateAt(op string, l *List, path []int, fn func() error) error {
+	if len(path) == 0 {
+		return fmt.Errorf("Item address cannot be blank")
+	}
+
+	return j.update(op, l, path[0], fn)
+}
+
+// update applies fn, which modifies item i in place, and records the
+// operation. Items appended by fn, such as the next instance of a
+// recurring item, are recorded as part of the same operation
+func (j *Journal) update(op string, l *List, i int, fn func() error) error {
+	if i <= 0 || i > len(*l) {
+		return fmt.Errorf("Item %d does not exist", i)
+	}
+
+	n := len(*l)
+	before := copyItem((*l)[i-1])
+	if err := fn(); err != nil {
+		return err
+	}
+
+	changes := []Change{{Index: i - 1, Before: before, After: copyItem((*l)[i-1])}}
+	for k := n; k < len(*l); k++ {
+		changes = append(changes, Change{Index: k, After: copyItem((*l)[k])})
+	}
+
+	j.record(op, 0, changes...)
+	return nil
+}
+
+// Undo reverses the most recent operation that was not undone yet
+// and returns the entry that was reversed
+func (j *Journal) Undo(l *List) (Entry, error) {
+	entries, done, _, err := j.stacks()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(done) == 0 {
+		return Entry{}, ErrNothingToUndo
+	}
+
+	ref := done[len(done)-1]
+	e := entries[ref]
+
+	// Reverse the changes in the opposite order they were applied
+	for k := len(e.Changes) - 1; k >= 0; k-- {
+		c := e.Changes[k]
+		if err := l.apply(c.Index, c.After, c.Before); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	j.record(OpUndo, ref+1)
+	return e, nil
+}
+
+// Redo replays the most recently undone operation
+// and returns the entry that was replayed
+func (j *Journal) Redo(l *List) (Entry, error) {
+	entries, _, undone, err := j.stacks()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(undone) == 0 {
+		return Entry{}, ErrNothingToRedo
+	}
+
+	ref := undone[len(undone)-1]
+	e := entries[ref]
+
+	for _, c := range e.Changes {
+		if err := l.apply(c.Index, c.Before, c.After); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	j.record(OpRedo, ref+1)
+	return e, nil
+}
+
+// stacks replays the journal and returns its entries along with the
+// positions of the operations that can be undone and redone
+func (j *Journal) stacks() ([]Entry, []int, []int, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	entries = append(entries, j.pending...)
+
+	var done, undone []int
+	for k, e := range entries {
+		switch e.Op {
+		case OpUndo:
+			if len(done) > 0 {
+				undone = append(undone, done[len(done)-1])
+				done = done[:len(done)-1]
+			}
+		case OpRedo:
+			if len(undone) > 0 {
+				done = append(done, undone[len(undone)-1])
+				undone = undone[:len(undone)-1]
+			}
+		default:
+			// A new operation discards the operations that could be redone
+			done = append(done, k)
+			undone = nil
+		}
+	}
+
+	return entries, done, undone, nil
+}
+
+// apply replaces the item at position i, expected to be from,
+// with to. A nil from inserts a new item and a nil to removes it.
+func (l *List) apply(i int, from, to *item) error {
+	ls := *l
+
+	if from == nil {
+		if i < 0 || i > len(ls) {
+			return ErrListChanged
+		}
+		ls = append(ls, item{})
+		copy(ls[i+1:], ls[i:])
+		ls[i] = *to
+		*l = ls
+		return nil
+	}
+
+	if i < 0 || i >= len(ls) || !matchItem(ls[i], *from) {
+		return ErrListChanged
+	}
+
+	if to == nil {
+		*l = append(ls[:i], ls[i+1:]...)
+		return nil
+	}
+
+	ls[i] = *to
+	return nil
+}
+
+// copyItem returns a pointer to a deep copy of the item
+func copyItem(t item) *item {
+	c := t.clone()
+	return &c
+}
+
+// matchItem reports whether the item at a position still looks like
+// the one recorded in the journal. Stores such as todo.txt don't keep
+// the full timestamps so only the description and status are compared
+func matchItem(a, b item) bool {
+	return a.Task == b.Task && a.Done == b.Done
+}
+
+// sameItem reports whether both items hold the same data
+func sameItem(a, b item) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
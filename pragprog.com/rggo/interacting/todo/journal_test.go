@@ -0,0 +1,140 @@
+package todo_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestJournalUndoRedo tests reversing and replaying journaled operations
+func TestJournalUndoRedo(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	l := todo.List{}
+
+	j := todo.NewJournal(filename)
+	j.Add(&l, "Task 1")
+	j.Add(&l, "Task 2")
+	j.Add(&l, "Task 3")
+	if err := j.Complete(&l, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Delete(&l, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(todo.JournalFile(filename)); err != nil {
+		t.Fatalf("Expected journal file: %s", err)
+	}
+
+	// Use a fresh journal to ensure the state comes from the file
+	j = todo.NewJournal(filename)
+
+	e, err := j.Undo(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Op != todo.OpDelete {
+		t.Errorf("Expected to undo %q, got %q instead.", todo.OpDelete, e.Op)
+	}
+	if len(l) != 3 || l[1].Task != "Task 2" {
+		t.Fatalf("Expected deleted task restored, got %v instead.", l)
+	}
+
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].Done {
+		t.Errorf("Expected task 1 to be pending after undo")
+	}
+
+	if _, err := j.Redo(&l); err != nil {
+		t.Fatal(err)
+	}
+	if !l[0].Done {
+		t.Errorf("Expected task 1 to be completed after redo")
+	}
+
+	// A new operation discards the remaining redo history
+	j.Add(&l, "Task 4")
+	if _, err := j.Redo(&l); !errors.Is(err, todo.ErrNothingToRedo) {
+		t.Errorf("Expected %q, got %q instead.", todo.ErrNothingToRedo, err)
+	}
+
+	if err := j.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 9 {
+		t.Errorf("Expected %d entries, got %d instead.", 9, len(entries))
+	}
+}
+
+// TestJournalListChanged tests that undo refuses to modify a list
+// that no longer matches the journal
+func TestJournalListChanged(t *testing.T) {
+	l := todo.List{}
+
+	j := todo.NewJournal(filepath.Join(t.TempDir(), "todo.json"))
+	j.Add(&l, "Task 1")
+
+	l.Edit(1, "Changed elsewhere")
+
+	if _, err := j.Undo(&l); !errors.Is(err, todo.ErrListChanged) {
+		t.Errorf("Expected %q, got %q instead.", todo.ErrListChanged, err)
+	}
+}
+
+// TestJournalIncompleteEntry tests that a partially written entry
+// at the end of the journal is ignored, and dropped when new
+// entries are committed
+func TestJournalIncompleteEntry(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	l := todo.List{}
+
+	j := todo.NewJournal(filename)
+	j.Add(&l, "Task 1")
+	if err := j.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(todo.JournalFile(filename), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"Op":"add","Ref":2,"Cha`)
+	f.Close()
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected %d entries, got %d instead.", 1, len(entries))
+	}
+
+	j.Add(&l, "Task 2")
+	if err := j.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, err = j.Entries(); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Changes[0].After.Task != "Task 2" {
+		t.Errorf("Expected %d entries, got %+v instead.", 2, entries)
+	}
+
+	if _, err := j.Undo(&l); err != nil || len(l) != 1 {
+		t.Errorf("Expected Task 2 undone, got %v: %v", l, err)
+	}
+}
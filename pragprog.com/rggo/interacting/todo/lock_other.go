@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package todo
+
+import "os"
+
+// lockFile is a no-op on platforms without advisory file locks
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without advisory file locks
+func unlockFile(f *os.File) error {
+	return nil
+}
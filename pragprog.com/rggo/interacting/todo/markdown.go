@@ -0,0 +1,116 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// mdTask matches a GitHub-style Markdown task list item
+var mdTask = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+)$`)
+
+// ExportMarkdown writes the list to w as a GitHub-style Markdown
+// task list. When group is true, items are grouped under a heading
+// for their first +project and items without a project come first
+func (l *List) ExportMarkdown(w io.Writer, group bool) error {
+	if !group {
+		return writeMarkdown(w, *l)
+	}
+
+	var projects []string
+	groups := map[string]List{}
+
+	for _, t := range *l {
+		p := ""
+		if ps := t.Projects(); len(ps) > 0 {
+			p = ps[0]
+		}
+
+		if _, ok := groups[p]; !ok && p != "" {
+			projects = append(projects, p)
+		}
+		groups[p] = append(groups[p], t)
+	}
+
+	if err := writeMarkdown(w, groups[""]); err != nil {
+		return err
+	}
+
+	for k, p := range projects {
+		if k > 0 || len(groups[""]) > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "## %s\n\n", strings.TrimPrefix(p, "+")); err != nil {
+			return err
+		}
+
+		if err := writeMarkdown(w, groups[p]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeMarkdown writes the items as Markdown task list entries
+func writeMarkdown(w io.Writer, l List) error {
+	return writeMarkdownLevel(w, l, "")
+}
+
+// writeMarkdownLevel writes the items with the given indentation,
+// nesting their subtasks below them
+func writeMarkdownLevel(w io.Writer, l List, indent string) error {
+	for _, t := range l {
+		mark := " "
+		if t.Done {
+			mark = "x"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s- [%s] %s\n", indent, mark, t.Task); err != nil {
+			return err
+		}
+
+		if err := writeMarkdownLevel(w, t.Children, indent+"  "); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportMarkdown reads the task list items found in a Markdown
+// document, such as a README or an issue body, ignoring other
+// content. Nested task lists become subtasks
+func ImportMarkdown(r io.Reader) (List, error) {
+	l := List{}
+	n := newNester(&l)
+	now := time.Now()
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		m := mdTask.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+
+		t := item{
+			Task:      strings.TrimSpace(m[2]),
+			CreatedAt: now,
+		}
+
+		if m[1] != " " {
+			t.Done = true
+			t.CompletedAt = now
+		}
+
+		n.add(indentation(s.Text()), t)
+	}
+
+	return l, s.Err()
+}
@@ -0,0 +1,71 @@
+package todo_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestExportMarkdown tests rendering the list as a Markdown task list
+func TestExportMarkdown(t *testing.T) {
+	l := todo.List{}
+	l.Add("Write docs +release")
+	l.Add("Water plants")
+	l.Add("Tag version +release")
+	l.Add("Fix login +web")
+	l.Complete(1)
+
+	testCases := []struct {
+		name  string
+		group bool
+		exp   string
+	}{
+		{"Flat", false, "- [x] Write docs +release\n- [ ] Water plants\n" +
+			"- [ ] Tag version +release\n- [ ] Fix login +web\n"},
+		{"Grouped", true, "- [ ] Water plants\n\n## release\n\n" +
+			"- [x] Write docs +release\n- [ ] Tag version +release\n\n" +
+			"## web\n\n- [ ] Fix login +web\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := l.ExportMarkdown(&out, tc.group); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tc.exp {
+				t.Errorf("Expected %q, got %q instead.", tc.exp, out.String())
+			}
+		})
+	}
+}
+
+// TestImportMarkdown tests reading the task list items of a document
+func TestImportMarkdown(t *testing.T) {
+	doc := `# Release checklist
+
+Some introduction text.
+
+- [x] Write docs
+- [ ] Tag version
+  * [X] Nested item
+- Not a task
+`
+
+	l, err := todo.ImportMarkdown(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 2 {
+		t.Fatalf("Expected %d items, got %d instead.", 2, len(l))
+	}
+
+	exp := "X 1: Write docs\n  2: Tag version (1/1)\n  X 2.1: Nested item\n"
+	if l.String() != exp {
+		t.Errorf("Expected %q, got %q instead.", exp, l.String())
+	}
+}
@@ -0,0 +1,193 @@
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Conflict is a change made to an item in both copies of a list
+// being merged that couldn't be resolved. The merged list keeps
+// our side of it
+type Conflict struct {
+	ID     string
+	Task   string
+	Field  string
+	Ours   string
+	Theirs string
+}
+
+// String prints out a one line description of the conflict
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: %s: ours %s, theirs %s", c.Task, c.Field, c.Ours, c.Theirs)
+}
+
+// mergeField is a field of an item merged independently of the others
+type mergeField struct {
+	name string
+	get  func(t item) any
+	set  func(dst *item, src item)
+}
+
+// mergeFields are the fields merged by Merge. The creation date
+// never changes and subtasks and work sessions are merged apart
+var mergeFields = []mergeField{
+	{"task", func(t item) any { return t.Task }, func(d *item, s item) { d.Task = s.Task }},
+	{"done", func(t item) any { return []any{t.Done, t.CompletedAt} },
+		func(d *item, s item) { d.Done, d.CompletedAt = s.Done, s.CompletedAt }},
+	{"priority", func(t item) any { return t.Priority }, func(d *item, s item) { d.Priority = s.Priority }},
+	{"due", func(t item) any { return t.Due }, func(d *item, s item) { d.Due = s.Due }},
+	{"recur", func(t item) any { return t.Recur }, func(d *item, s item) { d.Recur = s.Recur }},
+	{"notes", func(t item) any { return t.Notes }, func(d *item, s item) { d.Notes = s.Notes }},
+	{"deps", func(t item) any { return t.Deps }, func(d *item, s item) { d.Deps = s.Deps }},
+	{"remind", func(t item) any { return t.Remind }, func(d *item, s item) { d.Remind = s.Remind }},
+}
+
+// Merge performs a three way merge of two copies of a list, ours and
+// theirs, changed independently since their common ancestor base.
+// Items are matched by ID, and subtasks are merged the same way.
+// Items added or deleted on either side are added or deleted, and
+// fields changed on one side only take the changed value. Items
+// completed on both sides keep the earliest completion time. Items
+// changed on one side and deleted on the other are kept, and fields
+// changed differently on both sides keep our value. Both are
+// reported as conflicts. Merged items keep our order, followed by
+// the items added on their side
+func Merge(base, ours, theirs List) (List, []Conflict) {
+	baseItems := byID(base)
+	theirItems := byID(theirs)
+	ourItems := byID(ours)
+
+	merged := List{}
+	var conflicts []Conflict
+
+	for _, o := range ours {
+		b, inBase := baseItems[o.ID]
+		t, inTheirs := theirItems[o.ID]
+
+		switch {
+		case inTheirs:
+			if !inBase {
+				// Both sides added the same item
+				b = item{ID: o.ID, CreatedAt: o.CreatedAt}
+			}
+			m, cs := mergeItem(b, o, t)
+			merged = append(merged, m)
+			conflicts = append(conflicts, cs...)
+
+		case !inBase:
+			// Added on our side
+			merged = append(merged, o)
+
+		case !sameItem(b, o):
+			// Changed on our side, deleted on theirs
+			merged = append(merged, o)
+			conflicts = append(conflicts, Conflict{ID: o.ID, Task: o.Task,
+				Field: "item", Ours: "changed", Theirs: "deleted"})
+		}
+	}
+
+	for _, t := range theirs {
+		if _, ok := ourItems[t.ID]; ok {
+			continue
+		}
+
+		b, inBase := baseItems[t.ID]
+		switch {
+		case !inBase:
+			// Added on their side
+			merged = append(merged, t)
+
+		case !sameItem(b, t):
+			// Deleted on our side, changed on theirs
+			merged = append(merged, t)
+			conflicts = append(conflicts, Conflict{ID: t.ID, Task: t.Task,
+				Field: "item", Ours: "deleted", Theirs: "changed"})
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeItem merges the fields, subtasks and work sessions of an item
+func mergeItem(b, o, t item) (item, []Conflict) {
+	m := o.clone()
+	var conflicts []Conflict
+
+	for _, f := range mergeFields {
+		bv, ov, tv := f.get(b), f.get(o), f.get(t)
+
+		switch {
+		case sameValue(ov, tv), sameValue(bv, tv):
+			// Unchanged on their side, or changed the same way
+		case sameValue(bv, ov):
+			f.set(&m, t)
+		case f.name == "done" && o.Done && t.Done:
+			// Completed on both sides, first by them
+			if t.CompletedAt.Before(o.CompletedAt) {
+				f.set(&m, t)
+			}
+		default:
+			conflicts = append(conflicts, Conflict{ID: o.ID, Task: o.Task,
+				Field: f.name, Ours: jsonValue(ov), Theirs: jsonValue(tv)})
+		}
+	}
+
+	children, cs := Merge(b.Children, o.Children, t.Children)
+	if len(children) == 0 {
+		children = nil
+	}
+	m.Children = children
+	conflicts = append(conflicts, cs...)
+
+	m.Sessions = mergeSessions(o.Sessions, t.Sessions)
+
+	return m, conflicts
+}
+
+// mergeSessions returns the work sessions of both sides, matched by
+// their start time. Sessions stopped on either side are kept stopped
+func mergeSessions(ours, theirs []Session) []Session {
+	sessions := map[int64]Session{}
+	for _, s := range append(append([]Session{}, ours...), theirs...) {
+		k := s.Start.UnixNano()
+		if prev, ok := sessions[k]; ok && (s.End.IsZero() || (!prev.End.IsZero() && prev.End.After(s.End))) {
+			continue
+		}
+		sessions[k] = s
+	}
+
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	merged := make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		merged = append(merged, s)
+	}
+	sort.Slice(merged, func(a, b int) bool { return merged[a].Start.Before(merged[b].Start) })
+
+	return merged
+}
+
+// byID indexes the items of the list by ID
+func byID(l List) map[string]item {
+	m := make(map[string]item, len(l))
+	for _, t := range l {
+		m[t.ID] = t
+	}
+
+	return m
+}
+
+// sameValue reports whether both values encode to the same JSON,
+// so times are compared regardless of how they were decoded
+func sameValue(a, b any) bool {
+	return jsonValue(a) == jsonValue(b)
+}
+
+// jsonValue returns the JSON encoding of a field value
+func jsonValue(v any) string {
+	js, _ := json.Marshal(v)
+	return string(js)
+}
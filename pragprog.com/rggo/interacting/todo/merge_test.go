@@ -0,0 +1,162 @@
+package todo_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// copyList returns a deep copy of the list, as if
+// it was read from a copy of the file
+func copyList(t *testing.T, l todo.List) todo.List {
+	t.Helper()
+
+	js, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c todo.List
+	if err := json.Unmarshal(js, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// tasks returns the descriptions of the items of the list
+func tasks(l todo.List) []string {
+	var s []string
+	for _, t := range l {
+		s = append(s, t.Task)
+	}
+
+	return s
+}
+
+// TestMergeIndependent tests changes made on different items
+// of both copies are merged without conflicts
+func TestMergeIndependent(t *testing.T) {
+	base := todo.List{}
+	for _, task := range []string{"Task X", "Task Y", "Task Z"} {
+		base.Add(task)
+	}
+
+	ours := copyList(t, base)
+	ours.Add("Task A")
+	ours.Complete(1)
+
+	theirs := copyList(t, base)
+	theirs.Add("Task B")
+	theirs.Delete(2)
+	theirs.Edit(2, "Task Z", todo.WithNotes("Ask Bob"))
+
+	merged, conflicts := todo.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %v instead.", conflicts)
+	}
+
+	exp := []string{"Task X", "Task Z", "Task A", "Task B"}
+	if got := tasks(merged); len(got) != len(exp) || got[0] != exp[0] || got[1] != exp[1] || got[2] != exp[2] || got[3] != exp[3] {
+		t.Fatalf("Expected %v, got %v instead.", exp, got)
+	}
+
+	if !merged[0].Done || merged[1].Notes != "Ask Bob" {
+		t.Errorf("Expected changes from both sides, got %v", merged)
+	}
+}
+
+// TestMergeCompletedBoth tests an item completed on both sides
+// merges without conflicts and keeps the first completion
+func TestMergeCompletedBoth(t *testing.T) {
+	base := todo.List{}
+	base.Add("Task X")
+
+	theirs := copyList(t, base)
+	theirs.Complete(1)
+
+	ours := copyList(t, base)
+	ours.Complete(1)
+
+	merged, conflicts := todo.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %v instead.", conflicts)
+	}
+
+	if !merged[0].Done || !merged[0].CompletedAt.Equal(theirs[0].CompletedAt) {
+		t.Errorf("Expected completion time %v, got %v instead.", theirs[0].CompletedAt, merged[0].CompletedAt)
+	}
+}
+
+// TestMergeConflicts tests changes that can't be merged are reported
+func TestMergeConflicts(t *testing.T) {
+	base := todo.List{}
+	base.Add("Task X")
+	base.Add("Task Y")
+
+	ours := copyList(t, base)
+	ours.Edit(1, "Task X ours")
+	ours.Edit(2, "Task Y", todo.WithNotes("Still needed"))
+
+	theirs := copyList(t, base)
+	theirs.Edit(1, "Task X theirs")
+	theirs.Complete(1)
+	theirs.Delete(2)
+
+	merged, conflicts := todo.Merge(base, ours, theirs)
+
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected %d conflicts, got %v instead.", 2, conflicts)
+	}
+	if c := conflicts[0]; c.Field != "task" || c.Ours != `"Task X ours"` || c.Theirs != `"Task X theirs"` {
+		t.Errorf("Unexpected conflict %s", c)
+	}
+	if c := conflicts[1]; c.Field != "item" || c.Theirs != "deleted" {
+		t.Errorf("Unexpected conflict %s", c)
+	}
+
+	// Our side is kept, and the other changes are merged
+	if len(merged) != 2 || merged[0].Task != "Task X ours" || !merged[0].Done || merged[1].Notes != "Still needed" {
+		t.Errorf("Unexpected merged list %v", merged)
+	}
+}
+
+// TestMergeSubtasksAndSessions tests subtasks and work sessions
+// are merged by item
+func TestMergeSubtasksAndSessions(t *testing.T) {
+	start := time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	base := todo.List{}
+	base.Add("Release")
+	base.AddAt([]int{1}, "Write docs")
+
+	ours := copyList(t, base)
+	ours.StartAt([]int{1}, start)
+	ours.StopAt([]int{1}, start.Add(time.Hour))
+	ours.CompleteAt([]int{1, 1}, true)
+
+	theirs := copyList(t, base)
+	theirs.AddAt([]int{1}, "Tag version")
+	theirs.StartAt([]int{1}, start.Add(2*time.Hour))
+
+	merged, conflicts := todo.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %v instead.", conflicts)
+	}
+
+	children := merged[0].Children
+	if len(children) != 2 || !children[0].Done || children[1].Task != "Tag version" {
+		t.Errorf("Unexpected subtasks %v", children)
+	}
+
+	// Our completion of the only subtask completed the parent
+	if !merged[0].Done {
+		t.Errorf("Expected parent completed on our side to stay completed")
+	}
+
+	if s := merged[0].Sessions; len(s) != 2 || !s[1].Start.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("Unexpected sessions %v", s)
+	}
+}
@@ -0,0 +1,225 @@
+package todo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence kinds
+const (
+	RecurDaily   = "daily"
+	RecurWeekly  = "weekly"
+	RecurMonthly = "monthly"
+	RecurEvery   = "every"
+)
+
+// weekdays maps the abbreviations accepted in weekly rules
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Recurrence is a rule describing when a task repeats. Rules are
+// written as:
+//
+//	daily            every day after the due date
+//	weekly           every week after the due date
+//	weekly=mon,thu   on the given weekdays
+//	monthly          every month after the due date
+//	monthly=15       on the given day of the month
+//	every=3d         3 days (or weeks with "w") after completion
+type Recurrence struct {
+	Kind     string
+	Weekdays []time.Weekday
+	Day      int
+	Days     int
+}
+
+// ParseRecurrence parses a recurrence rule
+func ParseRecurrence(rule string) (Recurrence, error) {
+	kind, arg, hasArg := strings.Cut(strings.ToLower(strings.TrimSpace(rule)), "=")
+	r := Recurrence{Kind: kind}
+
+	switch {
+	case kind == RecurDaily && !hasArg:
+		return r, nil
+
+	case kind == RecurWeekly:
+		if !hasArg {
+			return r, nil
+		}
+		for _, d := range strings.Split(arg, ",") {
+			wd, ok := weekdays[d]
+			if !ok {
+				return Recurrence{}, fmt.Errorf("Invalid weekday %q in recurrence %q", d, rule)
+			}
+			r.Weekdays = append(r.Weekdays, wd)
+		}
+		return r, nil
+
+	case kind == RecurMonthly:
+		if !hasArg {
+			return r, nil
+		}
+		day, err := strconv.Atoi(arg)
+		if err != nil || day < 1 || day > 31 {
+			return Recurrence{}, fmt.Errorf("Invalid day %q in recurrence %q", arg, rule)
+		}
+		r.Day = day
+		return r, nil
+
+	case kind == RecurEvery && hasArg:
+		mult := 1
+		if n, ok := strings.CutSuffix(arg, "w"); ok {
+			arg, mult = n, 7
+		} else {
+			arg = strings.TrimSuffix(arg, "d")
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return Recurrence{}, fmt.Errorf("Invalid interval in recurrence %q", rule)
+		}
+		r.Days = n * mult
+		return r, nil
+	}
+
+	return Recurrence{}, fmt.Errorf("Invalid recurrence %q", rule)
+}
+
+// Next returns the first date of the rule after from
+func (r Recurrence) Next(from time.Time) time.Time {
+	from = day(from)
+
+	switch r.Kind {
+	case RecurDaily:
+		return from.AddDate(0, 0, 1)
+
+	case RecurWeekly:
+		if len(r.Weekdays) == 0 {
+			return from.AddDate(0, 0, 7)
+		}
+		for k := 1; ; k++ {
+			d := from.AddDate(0, 0, k)
+			for _, wd := range r.Weekdays {
+				if d.Weekday() == wd {
+					return d
+				}
+			}
+		}
+
+	case RecurMonthly:
+		if r.Day == 0 {
+			// Keep to the next month when it's shorter
+			return monthDay(from.Year(), from.Month()+1, from.Day(), from.Location())
+		}
+		d := monthDay(from.Year(), from.Month(), r.Day, from.Location())
+		if !d.After(from) {
+			d = monthDay(from.Year(), from.Month()+1, r.Day, from.Location())
+		}
+		return d
+	}
+
+	return from.AddDate(0, 0, r.Days)
+}
+
+// next returns the pending instance that follows the item when it
+// is completed at the given time. Intervals are counted from the
+// completion date; other rules follow the due date, skipping
+// occurrences missed before the completion. The instance keeps the
+// notes, dependencies and subtasks of the item, pending again
+func (t item) next(r Recurrence, completed time.Time) *item {
+	due := r.Next(completed)
+	rule := t.Recur
+
+	if r.Kind != RecurEvery && !t.Due.IsZero() {
+		// Monthly items keep to the day they were due on, and
+		// the instances clamped to a shorter month remember it
+		if r.Kind == RecurMonthly && r.Day == 0 {
+			r.Day = t.Due.Day()
+		}
+
+		due = r.Next(t.Due)
+		for !due.After(day(completed)) {
+			due = r.Next(due)
+		}
+
+		if r.Kind == RecurMonthly && due.Day() != r.Day {
+			rule = fmt.Sprintf("%s=%d", RecurMonthly, r.Day)
+		}
+	}
+
+	return &item{
+		ID:        newID(),
+		Task:      t.Task,
+		CreatedAt: completed,
+		Priority:  t.Priority,
+		Due:       due,
+		Recur:     rule,
+		Notes:     t.Notes,
+		Children:  renewed(t.Children, completed),
+		Deps:      append([]string(nil), t.Deps...),
+		Remind:    t.nextReminder(due),
+	}
+}
+
+// renewed returns pending copies of the subtasks of a recurring
+// item, with new IDs, for its next instance
+func renewed(l List, created time.Time) List {
+	if l == nil {
+		return nil
+	}
+
+	ls := make(List, len(l))
+	for k, c := range l {
+		ls[k] = item{
+			ID:        newID(),
+			Task:      c.Task,
+			CreatedAt: created,
+			Priority:  c.Priority,
+			Due:       c.Due,
+			Recur:     c.Recur,
+			Notes:     c.Notes,
+			Children:  renewed(c.Children, created),
+		}
+	}
+
+	return ls
+}
+
+// nextReminder returns the reminder of the instance due on the given
+// date, at the same time and as many days before it as the item's
+func (t item) nextReminder(due time.Time) time.Time {
+	if t.Remind.IsZero() || t.Due.IsZero() {
+		return time.Time{}
+	}
+
+	days := int(math.Round(day(t.Due).Sub(day(t.Remind)).Hours() / 24))
+	d := due.AddDate(0, 0, -days)
+
+	return time.Date(d.Year(), d.Month(), d.Day(), t.Remind.Hour(), t.Remind.Minute(), 0, 0, t.Remind.Location())
+}
+
+// day truncates t to the start of its day
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// monthDay returns the given day of the month, clamped to the
+// last day for shorter months
+func monthDay(year int, month time.Month, d int, loc *time.Location) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if d > last {
+		d = last
+	}
+
+	return time.Date(year, month, d, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,128 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestRecurrenceNext tests computing the next date of each rule
+func TestRecurrenceNext(t *testing.T) {
+	// 2024-01-31 is a Wednesday
+	from := time.Date(2024, 1, 31, 10, 0, 0, 0, time.Local)
+
+	testCases := []struct {
+		rule string
+		exp  string
+	}{
+		{"daily", "2024-02-01"},
+		{"weekly", "2024-02-07"},
+		{"weekly=mon,thu", "2024-02-01"},
+		{"weekly=wed", "2024-02-07"},
+		{"monthly", "2024-02-29"},
+		{"monthly=15", "2024-02-15"},
+		{"monthly=31", "2024-02-29"},
+		{"every=3d", "2024-02-03"},
+		{"every=2w", "2024-02-14"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if next := r.Next(from).Format("2006-01-02"); next != tc.exp {
+				t.Errorf("Expected %q, got %q instead.", tc.exp, next)
+			}
+		})
+	}
+}
+
+// TestParseRecurrenceInvalid tests rejecting invalid rules
+func TestParseRecurrenceInvalid(t *testing.T) {
+	for _, rule := range []string{"", "hourly", "weekly=funday", "monthly=32", "every", "every=0d"} {
+		if _, err := todo.ParseRecurrence(rule); err == nil {
+			t.Errorf("Expected error for rule %q", rule)
+		}
+	}
+}
+
+// TestCompleteRecurring tests that completing a recurring item
+// adds its next pending instance
+func TestCompleteRecurring(t *testing.T) {
+	l := todo.List{}
+
+	due := time.Now().AddDate(0, 0, -3)
+	l.Add("Review alerts", todo.WithDue(due), todo.WithRecurrence("daily"))
+
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 2 {
+		t.Fatalf("Expected %d items, got %d instead.", 2, len(l))
+	}
+
+	if !l[0].Done || l[1].Done {
+		t.Errorf("Expected the first item completed and the second pending")
+	}
+
+	// Missed occurrences are skipped
+	exp := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	if next := l[1].Due.Format("2006-01-02"); next != exp {
+		t.Errorf("Expected next due date %q, got %q instead.", exp, next)
+	}
+
+	// Completing the finished item again doesn't repeat it
+	l.Complete(1)
+	if len(l) != 2 {
+		t.Errorf("Expected %d items, got %d instead.", 2, len(l))
+	}
+}
+
+// TestCompleteRecurringMonthly tests monthly items keep to their
+// day after shorter months, and their instances keep the notes,
+// dependencies and subtasks
+func TestCompleteRecurringMonthly(t *testing.T) {
+	year := time.Now().Year() + 1
+
+	l := todo.List{}
+	l.Add("Collect receipts")
+	l.Add("Pay rent", todo.WithDue(time.Date(year, 1, 31, 0, 0, 0, 0, time.Local)),
+		todo.WithRecurrence("monthly"), todo.WithNotes("Account 1234"))
+	l.AddAt([]int{2}, "Transfer funds")
+	if err := l.DependAt([]int{2}, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	l.Complete(1)
+
+	for _, exp := range []time.Time{
+		time.Date(year, 3, 0, 0, 0, 0, 0, time.Local),
+		time.Date(year, 3, 31, 0, 0, 0, 0, time.Local),
+	} {
+		n := len(l)
+		if err := l.CompleteAt([]int{n}, false); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(l) != n+1 {
+			t.Fatalf("Expected %d items, got %d instead.", n+1, len(l))
+		}
+
+		prev, next := l[n-1], l[n]
+		if !next.Due.Equal(exp) {
+			t.Errorf("Expected next due date %s, got %s instead.", exp.Format("2006-01-02"), next.Due.Format("2006-01-02"))
+		}
+
+		if next.Notes != prev.Notes || len(next.Deps) != 1 || next.Deps[0] != prev.Deps[0] {
+			t.Errorf("Expected notes and dependencies kept, got %+v instead.", next)
+		}
+
+		if len(next.Children) != 1 || next.Children[0].Done || next.Children[0].ID == prev.Children[0].ID {
+			t.Errorf("Expected a new pending subtask, got %+v instead.", next.Children)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package todo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the file format written by Save.
+// Bump it along with a new migration whenever the item data changes
+// in a way older versions of the items can't be decoded into
+const SchemaVersion = 2
+
+// Migration upgrades the items of a file from one schema
+// version to the next one
+type Migration func(items json.RawMessage) (json.RawMessage, error)
+
+// migrations maps each schema version to the migration
+// upgrading it to the next version
+var migrations = map[int]Migration{}
+
+// RegisterMigration registers the migration from schema
+// version from to version from+1
+func RegisterMigration(from int, m Migration) {
+	if _, ok := migrations[from]; ok {
+		panic(fmt.Sprintf("todo: migration from version %d registered twice", from))
+	}
+	migrations[from] = m
+}
+
+func init() {
+	// Version 0 files are a bare array of items, which
+	// version 1 wraps in an envelope with the version
+	RegisterMigration(0, func(items json.RawMessage) (json.RawMessage, error) {
+		return items, nil
+	})
+
+	// Version 2 gives every item an ID, so copies of a list
+	// can be merged
+	RegisterMigration(1, func(items json.RawMessage) (json.RawMessage, error) {
+		var ls []map[string]any
+		if err := json.Unmarshal(items, &ls); err != nil {
+			return nil, err
+		}
+
+		addIDs(ls)
+		return json.Marshal(ls)
+	})
+}
+
+// addIDs sets an ID on the decoded items, and their subtasks, that
+// don't have one. The ID is derived from the item, so every copy of
+// a legacy list gets the same IDs and the copies can still be merged,
+// even when they diverged before being migrated
+func addIDs(ls []map[string]any) {
+	for _, t := range ls {
+		if id, _ := t["ID"].(string); id == "" {
+			created, _ := t["CreatedAt"].(string)
+			task, _ := t["Task"].(string)
+			t["ID"] = contentID(created, task)
+		}
+
+		children, _ := t["Children"].([]any)
+		var cs []map[string]any
+		for _, c := range children {
+			if m, ok := c.(map[string]any); ok {
+				cs = append(cs, m)
+			}
+		}
+		addIDs(cs)
+	}
+}
+
+// contentID returns the ID of an item derived from its creation
+// time and task. Creation times are stored to the nanosecond, so
+// they tell items apart
+func contentID(created, task string) string {
+	sum := sha256.Sum256([]byte(created + "\x00" + task))
+	return hex.EncodeToString(sum[:8])
+}
+
+// newID returns a random ID identifying an item
+// across copies of the list
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// ensureIDs sets a new ID on the items, and their subtasks,
+// that don't have one, such as items read from other formats
+func (l List) ensureIDs() {
+	for k := range l {
+		if l[k].ID == "" {
+			l[k].ID = newID()
+		}
+		l[k].Children.ensureIDs()
+	}
+}
+
+// envelope is the versioned JSON document written by Save
+type envelope struct {
+	Version int             `json:"version"`
+	Items   json.RawMessage `json:"items"`
+}
+
+// encodeList encodes the list in the current schema version
+func encodeList(l List) ([]byte, error) {
+	if l == nil {
+		l = List{}
+	}
+	l.ensureIDs()
+
+	items, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope{Version: SchemaVersion, Items: items})
+}
+
+// decodeList decodes a list of any known schema version,
+// running the migrations needed to bring it up to date
+func decodeList(data []byte) (List, error) {
+	var env envelope
+
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		// Legacy files hold the bare array of items
+		env.Items = data
+	} else if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+
+	if env.Version > SchemaVersion {
+		return nil, fmt.Errorf("List file version %d is newer than the supported version %d", env.Version, SchemaVersion)
+	}
+
+	for v := env.Version; v < SchemaVersion; v++ {
+		m, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("No migration from list file version %d", v)
+		}
+
+		items, err := m(env.Items)
+		if err != nil {
+			return nil, fmt.Errorf("Migrating list file from version %d: %w", v, err)
+		}
+		env.Items = items
+	}
+
+	var l List
+	if len(env.Items) > 0 {
+		if err := json.Unmarshal(env.Items, &l); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,146 @@
+package todo_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestSaveVersion tests that Save writes the schema version
+// along with the items
+func TestSaveVersion(t *testing.T) {
+	tf := filepath.Join(t.TempDir(), "todo.json")
+
+	l := todo.List{}
+	l.Add("New Task")
+	if err := l.Save(tf); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Version int
+		Items   []json.RawMessage
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Version != todo.SchemaVersion {
+		t.Errorf("Expected version %d, got %d instead.", todo.SchemaVersion, doc.Version)
+	}
+	if len(doc.Items) != 1 {
+		t.Errorf("Expected 1 item, got %d instead.", len(doc.Items))
+	}
+}
+
+// TestGetLegacy tests that files holding a bare array of items,
+// written before the schema was versioned, are still read
+func TestGetLegacy(t *testing.T) {
+	tf := filepath.Join(t.TempDir(), "todo.json")
+
+	legacy := `[{"Task":"Old Task","Done":true,"CreatedAt":"2024-01-02T10:00:00Z",` +
+		`"CompletedAt":"2024-01-03T10:00:00Z","Due":"0001-01-01T00:00:00Z"}]`
+	if err := os.WriteFile(tf, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := todo.List{}
+	if err := l.Get(tf); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 1 || l[0].Task != "Old Task" || !l[0].Done {
+		t.Fatalf("Unexpected list read from legacy file: %+v", l)
+	}
+
+	// Items get an ID when they're migrated
+	if l[0].ID == "" {
+		t.Errorf("Expected migrated item to have an ID")
+	}
+
+	// Saving upgrades the file to the current version
+	if err := l.Save(tf); err != nil {
+		t.Fatal(err)
+	}
+
+	l2 := todo.List{}
+	if err := l2.Get(tf); err != nil {
+		t.Fatal(err)
+	}
+	if l2[0].ID != l[0].ID || l2[0].Task != l[0].Task || !l2[0].CreatedAt.Equal(l[0].CreatedAt) {
+		t.Errorf("Expected %+v, got %+v instead.", l[0], l2[0])
+	}
+}
+
+// TestGetNewerVersion tests that files written by a newer
+// version of the schema are rejected
+func TestGetNewerVersion(t *testing.T) {
+	tf := filepath.Join(t.TempDir(), "todo.json")
+
+	if err := os.WriteFile(tf, []byte(`{"version":999,"items":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := todo.List{}
+	err := l.Get(tf)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected newer version error, got %v instead.", err)
+	}
+}
+
+// TestMergeLegacy tests copies of a legacy file get the same IDs
+// when they're migrated, so merging them doesn't duplicate items
+func TestMergeLegacy(t *testing.T) {
+	tf := filepath.Join(t.TempDir(), "todo.json")
+
+	legacy := `[{"Task":"Old Task","CreatedAt":"2024-01-02T10:00:00Z",` +
+		`"Children":[{"Task":"Step","CreatedAt":"2024-01-02T11:00:00Z"}]}]`
+	if err := os.WriteFile(tf, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var copies [3]todo.List
+	for k := range copies {
+		if err := copies[k].Get(tf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merged, conflicts := todo.Merge(copies[0], copies[1], copies[2])
+	if len(merged) != 1 || len(merged[0].Children) != 1 || len(conflicts) != 0 {
+		t.Errorf("Expected 1 item with 1 subtask and no conflicts, got %+v and %v instead.", merged, conflicts)
+	}
+
+	// Copies that diverged before being migrated still match
+	diverged := `[{"Task":"Old Task","CreatedAt":"2024-01-02T10:00:00Z"},` +
+		`{"Task":"Other Task","CreatedAt":"2024-01-03T10:00:00Z"}]`
+	if err := os.WriteFile(tf, []byte(diverged), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for k := range copies {
+		if err := copies[k].Get(tf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ours := filepath.Join(t.TempDir(), "todo.json")
+	if err := os.WriteFile(ours, []byte(`[{"Task":"Other Task","CreatedAt":"2024-01-03T10:00:00Z"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := copies[1].Get(ours); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, conflicts = todo.Merge(copies[0], copies[1], copies[2])
+	if len(merged) != 1 || merged[0].Task != "Other Task" || len(conflicts) != 0 {
+		t.Errorf("Expected only the other task, got %+v and %v instead.", merged, conflicts)
+	}
+}
@@ -0,0 +1,140 @@
+package todo
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Match is a search result
+type Match struct {
+	// Path is the position of the item, and of its parents, and
+	// Address the same as shown in listings, such as 3.2
+	Path    []int
+	Address string
+	Task    string
+	Done    bool
+	Score   float64
+}
+
+// Search ranks the items, and their subtasks, by how well they match
+// the query. The query is compared against the task description,
+// notes and tags, tolerating typos as well as partial words.
+// Completed items are skipped unless includeDone is true
+func (l *List) Search(query string, includeDone bool) []Match {
+	q := strings.ToLower(strings.TrimSpace(query))
+	qTokens := words(q)
+	if len(qTokens) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	l.walk(nil, func(path []int, t item) {
+		if t.Done && !includeDone {
+			return
+		}
+
+		text := strings.ToLower(t.Task + " " + t.Notes)
+		tokens := words(text)
+
+		score := 0.0
+		for _, qt := range qTokens {
+			score += bestScore(qt, tokens)
+		}
+
+		// Reward matching the whole query as typed
+		if len(qTokens) > 1 && strings.Contains(text, q) {
+			score += 1
+		}
+
+		if score > 0 {
+			matches = append(matches, Match{Path: path, Address: FormatAddress(path),
+				Task: t.Task, Done: t.Done, Score: score})
+		}
+	})
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	return matches
+}
+
+// bestScore returns the score of the best match of the query
+// token q among tokens: 1 for an exact match, 0.8 for a partial
+// match and less for matches within a few typos
+func bestScore(q string, tokens []string) float64 {
+	best := 0.0
+
+	for _, t := range tokens {
+		s := 0.0
+		switch {
+		case t == q:
+			s = 1
+		case strings.Contains(t, q):
+			s = 0.8
+		default:
+			if d := distance(q, t); d <= maxTypos(q) {
+				s = 0.6 - 0.1*float64(d)
+			}
+		}
+
+		if s > best {
+			best = s
+		}
+	}
+
+	return best
+}
+
+// maxTypos returns how many typos are tolerated for the token
+func maxTypos(q string) int {
+	switch n := len([]rune(q)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// words splits s into words, dropping the + and @ of tags
+// and any other punctuation
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// distance returns the edit distance between a and b, counting
+// insertions, deletions, substitutions and transpositions of
+// adjacent characters as one edit each
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
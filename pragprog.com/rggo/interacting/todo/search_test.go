@@ -0,0 +1,52 @@
+package todo_test
+
+import (
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestSearch tests ranking items against queries
+func TestSearch(t *testing.T) {
+	l := todo.List{}
+	l.Add("Rotate TLS certificates +ops")
+	l.Add("Review alerts @oncall")
+	l.Add("Buy groceries", todo.WithNotes("milk and certified coffee"))
+	l.Add("Certificate renewal for staging +ops")
+	l.AddAt([]int{4}, "Renew the staging wildcard certificate")
+	l.Complete(2)
+
+	testCases := []struct {
+		name        string
+		query       string
+		includeDone bool
+		exp         []string
+	}{
+		{"ExactWord", "rotate", false, []string{"1"}},
+		{"Typo", "rotaet", false, []string{"1"}},
+		{"Substring", "certif", false, []string{"1", "3", "4", "4.1"}},
+		{"Tag", "ops", false, []string{"1", "4"}},
+		{"Notes", "coffee", false, []string{"3"}},
+		{"ExcludeDone", "alerts", false, nil},
+		{"IncludeDone", "alerts", true, []string{"2"}},
+		{"Ranking", "certificate renewal", false, []string{"4", "4.1", "1"}},
+		{"Subtask", "wildcard", false, []string{"4.1"}},
+		{"NoMatch", "zebra", true, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := l.Search(tc.query, tc.includeDone)
+
+			if len(m) != len(tc.exp) {
+				t.Fatalf("Expected %d matches, got %v instead.", len(tc.exp), m)
+			}
+
+			for k, addr := range tc.exp {
+				if m[k].Address != addr {
+					t.Errorf("Expected item %s at position %d, got %s instead.", addr, k, m[k].Address)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Select resolves a selection into the paths of the items it
+// matches, sorted by address. A selection is either a comma
+// separated list of addresses and ranges, such as "1-4,7,9.2-3",
+// or a filter expression combining the terms "all", "done",
+// "pending" and "older than N[d|w]", such as "done older than 30d".
+// Filters match the items of the list itself, not their subtasks.
+// Items count as older by their completion date when they're done
+// and by their creation date otherwise
+func (l *List) Select(sel string, now time.Time) ([][]int, error) {
+	sel = strings.TrimSpace(sel)
+	if sel == "" {
+		return nil, fmt.Errorf("Item selection cannot be blank")
+	}
+
+	var paths [][]int
+	var err error
+
+	if sel[0] >= '0' && sel[0] <= '9' {
+		paths, err = l.selectAddresses(sel)
+	} else {
+		paths, err = l.selectFilter(sel, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(paths, slices.Compare)
+	return slices.CompactFunc(paths, slices.Equal), nil
+}
+
+// selectAddresses resolves a list of addresses and ranges
+func (l *List) selectAddresses(sel string) ([][]int, error) {
+	var paths [][]int
+
+	for _, part := range strings.Split(sel, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+
+		first, err := ParseAddress(from)
+		if err != nil {
+			return nil, err
+		}
+
+		last := first
+		if isRange {
+			if last, err = ParseAddress(to); err != nil {
+				return nil, err
+			}
+
+			// Ranges of subtasks can be shortened, like 3.1-4
+			if len(last) == 1 && len(first) > 1 {
+				last = append(slices.Clone(first[:len(first)-1]), last[0])
+			}
+
+			// Both ends of a range must be items of the same list
+			d := len(first) - 1
+			if len(last) != len(first) || !slices.Equal(first[:d], last[:d]) || last[d] < first[d] {
+				return nil, fmt.Errorf("Invalid range %q", strings.TrimSpace(part))
+			}
+		}
+
+		d := len(first) - 1
+		for n := first[d]; n <= last[d]; n++ {
+			path := append(slices.Clone(first[:d]), n)
+			if _, _, err := l.at(path); err != nil {
+				return nil, err
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// selectFilter resolves a filter expression
+func (l *List) selectFilter(sel string, now time.Time) ([][]int, error) {
+	var filters []func(t item) bool
+
+	terms := strings.Fields(strings.ToLower(sel))
+	for k := 0; k < len(terms); k++ {
+		switch terms[k] {
+		case "all":
+		case "done":
+			filters = append(filters, func(t item) bool { return t.Done })
+		case "pending":
+			filters = append(filters, func(t item) bool { return !t.Done })
+		case "older":
+			if k+2 >= len(terms) || terms[k+1] != "than" {
+				return nil, fmt.Errorf("Invalid filter %q: use older than N[d|w]", sel)
+			}
+
+			age, err := parseAge(terms[k+2])
+			if err != nil {
+				return nil, err
+			}
+			k += 2
+
+			cutoff := now.Add(-age)
+			filters = append(filters, func(t item) bool {
+				if t.Done {
+					return t.CompletedAt.Before(cutoff)
+				}
+				return t.CreatedAt.Before(cutoff)
+			})
+		default:
+			return nil, fmt.Errorf("Unknown filter term %q", terms[k])
+		}
+	}
+
+	var paths [][]int
+
+items:
+	for k, t := range *l {
+		for _, f := range filters {
+			if !f(t) {
+				continue items
+			}
+		}
+		paths = append(paths, []int{k + 1})
+	}
+
+	return paths, nil
+}
+
+// parseAge parses an age such as 30d or 2w
+func parseAge(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("Invalid age %q: use N[d|w]", s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid age %q: use N[d|w]", s)
+	}
+
+	switch s[len(s)-1] {
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	}
+
+	return 0, fmt.Errorf("Invalid age %q: use N[d|w]", s)
+}
+
+// CompleteAll completes the pending items at paths, and their parents
+// whose subtasks are all done when parents is set, and records them
+// as a single operation, so they're undone together. Items already
+// completed are left as they are
+func (j *Journal) CompleteAll(l *List, paths [][]int, parents bool) error {
+	return j.batch(OpComplete, l, paths, func(b *Journal, path []int) error {
+		ls, k, err := l.at(path)
+		if err != nil || (*ls)[k].Done {
+			return err
+		}
+
+		return b.CompleteAt(l, path, parents)
+	})
+}
+
+// DeleteAll deletes the items at paths and records them as a single
+// operation. All paths are resolved before any item is deleted, so
+// deleting an item never shifts the others being deleted
+func (j *Journal) DeleteAll(l *List, paths [][]int) error {
+	return j.batch(OpDelete, l, paths, func(b *Journal, path []int) error {
+		return b.DeleteAt(l, path)
+	})
+}
+
+// batch applies fn to each path, from the last address to the first,
+// and records the changes as a single entry. The list is left
+// unchanged when any of them fails
+func (j *Journal) batch(op string, l *List, paths [][]int, fn func(b *Journal, path []int) error) error {
+	paths = slices.Clone(paths)
+	slices.SortFunc(paths, func(a, b []int) int { return slices.Compare(b, a) })
+
+	saved := make(List, len(*l))
+	for k, t := range *l {
+		saved[k] = t.clone()
+	}
+
+	b := &Journal{filename: j.filename, user: j.user}
+	for _, path := range paths {
+		if err := fn(b, path); err != nil {
+			*l = saved
+			return err
+		}
+	}
+
+	var changes []Change
+	for _, e := range b.pending {
+		changes = append(changes, e.Changes...)
+	}
+
+	if len(changes) > 0 {
+		j.record(op, 0, changes...)
+	}
+	return nil
+}
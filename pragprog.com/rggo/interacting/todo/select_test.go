@@ -0,0 +1,137 @@
+package todo_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestSelect tests resolving selections into item paths
+func TestSelect(t *testing.T) {
+	now := time.Now()
+
+	l := newNestedList(t)
+	for _, task := range []string{"Task 3", "Task 4", "Task 5"} {
+		l.Add(task)
+	}
+	l.Complete(2)
+	l.Complete(4)
+	l[1].CompletedAt = now.AddDate(0, 0, -40)
+	l[2].CreatedAt = now.AddDate(0, 0, -40)
+
+	testCases := []struct {
+		name string
+		sel  string
+		exp  [][]int
+	}{
+		{"Single", "3", [][]int{{3}}},
+		{"RangesAndLists", "4-5, 2,1.1-2,4", [][]int{{1, 1}, {1, 2}, {2}, {4}, {5}}},
+		{"All", "all", [][]int{{1}, {2}, {3}, {4}, {5}}},
+		{"Done", "done", [][]int{{2}, {4}}},
+		{"Pending", "pending", [][]int{{1}, {3}, {5}}},
+		{"DoneOlder", "all done older than 30d", [][]int{{2}}},
+		{"PendingOlder", "pending older than 2w", [][]int{{3}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, err := l.Select(tc.sel, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(paths, tc.exp) {
+				t.Errorf("Expected %v, got %v instead.", tc.exp, paths)
+			}
+		})
+	}
+
+	for _, sel := range []string{"", "4-2", "1.1-2.1", "9", "1-9", "old", "older than", "older than 3m", "sorted"} {
+		if _, err := l.Select(sel, now); err == nil {
+			t.Errorf("Expected error for selection %q", sel)
+		}
+	}
+}
+
+// TestDeleteAll tests deleting several items at once as
+// a single operation that can be undone
+func TestDeleteAll(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	l := todo.List{}
+
+	j := todo.NewJournal(filename)
+	for _, task := range []string{"Task 1", "Task 2", "Task 3", "Task 4", "Task 5"} {
+		j.Add(&l, task)
+	}
+	if err := j.AddAt(&l, []int{3}, "Subtask"); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := l.Select("1-2,4,3.1", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.DeleteAll(&l, paths); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 2 || l[0].Task != "Task 3" || l[1].Task != "Task 5" || len(l[0].Children) != 0 {
+		t.Fatalf("Unexpected list after deleting: %v", l)
+	}
+
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 5 || l[1].Task != "Task 2" || l[3].Task != "Task 4" || len(l[2].Children) != 1 {
+		t.Fatalf("Expected all items restored, got %v instead.", l)
+	}
+
+	// A failure leaves the list unchanged
+	if err := j.DeleteAll(&l, [][]int{{1}, {9}}); err == nil {
+		t.Fatal("Expected error deleting missing item")
+	}
+	if len(l) != 5 {
+		t.Errorf("Expected %d items after failure, got %d instead.", 5, len(l))
+	}
+}
+
+// TestCompleteAll tests completing several items at once
+func TestCompleteAll(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	l := todo.List{}
+
+	j := todo.NewJournal(filename)
+	for _, task := range []string{"Task 1", "Task 2", "Task 3"} {
+		j.Add(&l, task)
+	}
+	if err := j.Complete(&l, 2); err != nil {
+		t.Fatal(err)
+	}
+	completed := l[1].CompletedAt
+
+	if err := j.CompleteAll(&l, [][]int{{1}, {2}, {3}}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, item := range l {
+		if !item.Done {
+			t.Errorf("Expected item %d to be completed", k+1)
+		}
+	}
+	if !l[1].CompletedAt.Equal(completed) {
+		t.Errorf("Expected completed item to be left as it was")
+	}
+
+	// Undo reverts the whole operation but not the earlier completion
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].Done || !l[1].Done || l[2].Done {
+		t.Errorf("Unexpected list after undo: %v", l)
+	}
+}
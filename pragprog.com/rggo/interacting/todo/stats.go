@@ -0,0 +1,173 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Period counts the items created and completed in a day or week
+type Period struct {
+	Period    string `json:"period"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+	Open      int    `json:"open"`
+}
+
+// OpenTask is a pending item reported by Stats
+type OpenTask struct {
+	Address   string    `json:"address"`
+	Task      string    `json:"task"`
+	CreatedAt time.Time `json:"created_at"`
+	AgeDays   int       `json:"age_days"`
+}
+
+// Stats summarizes the activity of a list
+type Stats struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Completed int `json:"completed"`
+	// AvgCompletion is the average time to complete an item, of the
+	// items that have both a creation and a completion time
+	AvgCompletion time.Duration `json:"avg_completion_ns"`
+	Daily         []Period      `json:"daily"`
+	Weekly        []Period      `json:"weekly"`
+	Oldest        []OpenTask    `json:"oldest"`
+}
+
+// Stats computes the statistics of the list, including subtasks,
+// for the given number of days up to now. Daily periods also report
+// how many items were open at the end of each day, for burndown charts
+func (l *List) Stats(now time.Time, days int) Stats {
+	var s Stats
+	var all []item
+	var open []OpenTask
+	var total time.Duration
+	timed := 0
+
+	l.walk(nil, func(path []int, t item) {
+		all = append(all, t)
+		s.Total++
+
+		if !t.Done {
+			s.Pending++
+			open = append(open, OpenTask{
+				Address:   FormatAddress(path),
+				Task:      t.Task,
+				CreatedAt: t.CreatedAt,
+				AgeDays:   int(now.Sub(t.CreatedAt).Hours() / 24),
+			})
+			return
+		}
+
+		s.Completed++
+
+		// Items imported from other formats can miss either time
+		if !t.CreatedAt.IsZero() && !t.CompletedAt.IsZero() {
+			total += t.CompletedAt.Sub(t.CreatedAt)
+			timed++
+		}
+	})
+
+	if timed > 0 {
+		s.AvgCompletion = total / time.Duration(timed)
+	}
+
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].CreatedAt.Before(open[j].CreatedAt)
+	})
+	s.Oldest = open[:min(len(open), 5)]
+
+	first := day(now).AddDate(0, 0, 1-days)
+	for d := first; !d.After(now); d = d.AddDate(0, 0, 1) {
+		s.Daily = append(s.Daily, count(all, d.Format("2006-01-02"), d, d.AddDate(0, 0, 1)))
+	}
+
+	// Weeks start on Monday
+	week := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	for ; !week.After(now); week = week.AddDate(0, 0, 7) {
+		y, w := week.ISOWeek()
+		s.Weekly = append(s.Weekly, count(all, fmt.Sprintf("%d-W%02d", y, w), week, week.AddDate(0, 0, 7)))
+	}
+
+	return s
+}
+
+// count returns the activity of the items between start and end
+func count(all []item, name string, start, end time.Time) Period {
+	p := Period{Period: name}
+
+	for _, t := range all {
+		if !t.CreatedAt.Before(start) && t.CreatedAt.Before(end) {
+			p.Created++
+		}
+
+		if t.Done && !t.CompletedAt.Before(start) && t.CompletedAt.Before(end) {
+			p.Completed++
+		}
+
+		if t.CreatedAt.Before(end) && (!t.Done || !t.CompletedAt.Before(end)) {
+			p.Open++
+		}
+	}
+
+	return p
+}
+
+// String prints out the statistics as a text report
+// Implements the fmt.Stringer interface
+func (s Stats) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Tasks: %d total, %d pending, %d completed\n", s.Total, s.Pending, s.Completed)
+	fmt.Fprintf(&b, "Average time to complete: %s\n", formatDuration(s.AvgCompletion))
+
+	fmt.Fprintf(&b, "\nPer day:     created completed\n")
+	for _, p := range s.Daily {
+		fmt.Fprintf(&b, "  %-10s %7d %9d\n", p.Period, p.Created, p.Completed)
+	}
+
+	fmt.Fprintf(&b, "\nPer week:    created completed\n")
+	for _, p := range s.Weekly {
+		fmt.Fprintf(&b, "  %-10s %7d %9d\n", p.Period, p.Created, p.Completed)
+	}
+
+	if len(s.Oldest) > 0 {
+		fmt.Fprintf(&b, "\nOldest open tasks:\n")
+		for _, t := range s.Oldest {
+			fmt.Fprintf(&b, "  %s: %s (%d days old)\n", t.Address, t.Task, t.AgeDays)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nBurndown (open tasks at the end of each day):\n")
+	most := 0
+	for _, p := range s.Daily {
+		most = max(most, p.Open)
+	}
+	for _, p := range s.Daily {
+		// Scale the bars to at most 50 characters
+		bar := p.Open
+		if most > 50 {
+			bar = p.Open * 50 / most
+		}
+		fmt.Fprintf(&b, "  %s %s %d\n", p.Period, strings.Repeat("#", bar), p.Open)
+	}
+
+	return b.String()
+}
+
+// formatDuration prints out a duration in days and hours
+func formatDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
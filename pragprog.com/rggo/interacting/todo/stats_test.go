@@ -0,0 +1,76 @@
+package todo_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestStats tests the statistics computed from creation and
+// completion times
+func TestStats(t *testing.T) {
+	now := time.Date(2024, 3, 14, 12, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Old task")
+	l.Add("Done task")
+	l.Add("Parent")
+	l.AddAt([]int{3}, "Child")
+	l.Add("Imported task")
+
+	l[0].CreatedAt = now.AddDate(0, 0, -10)
+	l[1].CreatedAt = now.AddDate(0, 0, -3)
+	l[1].Done = true
+	l[1].CompletedAt = now.AddDate(0, 0, -1)
+	l[2].CreatedAt = now.AddDate(0, 0, -2)
+	l[2].Children[0].CreatedAt = now.AddDate(0, 0, -2)
+
+	// Completed items without a creation time don't count
+	// towards the average completion time
+	l[3].CreatedAt = time.Time{}
+	l[3].Done = true
+	l[3].CompletedAt = now.AddDate(0, 0, -20)
+
+	s := l.Stats(now, 7)
+
+	if s.Total != 5 || s.Pending != 3 || s.Completed != 2 {
+		t.Errorf("Unexpected totals %d/%d/%d", s.Total, s.Pending, s.Completed)
+	}
+
+	if s.AvgCompletion != 48*time.Hour {
+		t.Errorf("Expected %s, got %s instead.", 48*time.Hour, s.AvgCompletion)
+	}
+
+	if len(s.Daily) != 7 {
+		t.Fatalf("Expected %d days, got %d instead.", 7, len(s.Daily))
+	}
+
+	// 2024-03-11 is three days ago
+	d := s.Daily[3]
+	if d.Period != "2024-03-11" || d.Created != 1 || d.Completed != 0 || d.Open != 2 {
+		t.Errorf("Unexpected period %+v", d)
+	}
+
+	// The last day has everything but the completed task open
+	if d := s.Daily[6]; d.Open != 3 {
+		t.Errorf("Expected %d open tasks, got %d instead.", 3, d.Open)
+	}
+
+	if s.Oldest[0].Task != "Old task" || s.Oldest[0].AgeDays != 10 {
+		t.Errorf("Unexpected oldest task %+v", s.Oldest[0])
+	}
+	if s.Oldest[2].Address != "3.1" {
+		t.Errorf("Expected subtask address %q, got %q instead.", "3.1", s.Oldest[2].Address)
+	}
+
+	// 2024-03-08 to 2024-03-14 spans two weeks starting on Monday
+	if len(s.Weekly) != 2 || s.Weekly[1].Period != "2024-W11" {
+		t.Errorf("Unexpected weeks %+v", s.Weekly)
+	}
+
+	if !strings.Contains(s.String(), "  2024-03-14 ### 3\n") {
+		t.Errorf("Expected burndown bar in report, got %q instead.", s.String())
+	}
+}
@@ -0,0 +1,168 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Status summarizes the pending items of a list, including subtasks
+type Status struct {
+	Pending  int `json:"pending"`
+	DueToday int `json:"due_today"`
+	Overdue  int `json:"overdue"`
+}
+
+// Status counts the pending items and those due today or overdue at now
+func (l *List) Status(now time.Time) Status {
+	var st Status
+	today := day(now)
+
+	l.walk(nil, func(path []int, t item) {
+		if t.Done {
+			return
+		}
+
+		st.Pending++
+		switch {
+		case t.Due.IsZero():
+		case t.Due.Before(today):
+			st.Overdue++
+		case t.Due.Equal(today):
+			st.DueToday++
+		}
+	})
+
+	return st
+}
+
+// String prints out a one line summary of the status, short
+// enough for a shell prompt
+func (st Status) String() string {
+	return fmt.Sprintf("%d pending, %d due today, %d overdue", st.Pending, st.DueToday, st.Overdue)
+}
+
+// WithReminder sets the time a reminder of the item is due
+func WithReminder(at time.Time) Option {
+	return func(t *item) {
+		t.Remind = at
+	}
+}
+
+// Reminder is a reminder of a pending item
+type Reminder struct {
+	ID      string
+	Address string
+	Task    string
+	At      time.Time
+	Due     time.Time
+}
+
+// String prints out the reminder
+func (r Reminder) String() string {
+	return r.Describe(DefaultDateLayout)
+}
+
+// Describe prints out the reminder with its due date
+// in the given layout
+func (r Reminder) Describe(layout string) string {
+	s := fmt.Sprintf("Reminder: %s: %s", r.Address, r.Task)
+	if !r.Due.IsZero() {
+		s += " (due " + r.Due.Format(layout) + ")"
+	}
+
+	return s
+}
+
+// Reminders returns the reminders of the pending items whose
+// reminder time has passed at now, unless they were announced
+func (l *List) Reminders(now time.Time, announced Announced) []Reminder {
+	var rs []Reminder
+
+	l.walk(nil, func(path []int, t item) {
+		if t.Done || t.Remind.IsZero() || t.Remind.After(now) {
+			return
+		}
+		if at, ok := announced[t.ID]; ok && at.Equal(t.Remind) {
+			return
+		}
+
+		rs = append(rs, Reminder{
+			ID:      t.ID,
+			Address: FormatAddress(path),
+			Task:    t.Task,
+			At:      t.Remind,
+			Due:     t.Due,
+		})
+	})
+
+	return rs
+}
+
+// RemindFile returns the name of the file kept next to the list file
+// to track the reminders already announced. It only holds item IDs
+// and times, so it's never encrypted
+func RemindFile(filename string) string {
+	return filename + ".reminded"
+}
+
+// Announced maps the IDs of the items whose reminders were announced
+// to their reminder times. A reminder moved to another time is
+// announced again
+type Announced map[string]time.Time
+
+// GetAnnounced reads the reminders announced from the file
+func GetAnnounced(filename string) (Announced, error) {
+	a := Announced{}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return a, nil
+		}
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return a, nil
+	}
+
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Announce records the reminders as announced and forgets the
+// items that no longer have a pending reminder in the list
+func (a Announced) Announce(l *List, rs []Reminder) {
+	for _, r := range rs {
+		a[r.ID] = r.At
+	}
+
+	keep := map[string]bool{}
+	l.walk(nil, func(path []int, t item) {
+		if !t.Done && !t.Remind.IsZero() {
+			keep[t.ID] = true
+		}
+	})
+
+	for id := range a {
+		if !keep[id] {
+			delete(a, id)
+		}
+	}
+}
+
+// Save writes the reminders announced to the file
+func (a Announced) Save(filename string) error {
+	js, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filename, js, 0644)
+}
@@ -0,0 +1,105 @@
+package todo_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestStatus tests counting pending, due today and overdue items
+func TestStatus(t *testing.T) {
+	now := time.Date(2024, 3, 14, 18, 0, 0, 0, time.Local)
+	today := time.Date(2024, 3, 14, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Pay rent", todo.WithDue(today.AddDate(0, 0, -1)))
+	l.Add("Call plumber", todo.WithDue(today))
+	l.Add("Book flights", todo.WithDue(today.AddDate(0, 0, 7)))
+	l.Add("Read book")
+	l.Add("Paid taxes", todo.WithDue(today.AddDate(0, 0, -3)))
+	l.Complete(5)
+	l.AddAt([]int{3}, "Compare prices", todo.WithDue(today))
+
+	expected := todo.Status{Pending: 5, DueToday: 2, Overdue: 1}
+	if st := l.Status(now); st != expected {
+		t.Errorf("Expected %+v, got %+v instead.", expected, st)
+	}
+
+	if s := expected.String(); s != "5 pending, 2 due today, 1 overdue" {
+		t.Errorf("Unexpected status %q", s)
+	}
+}
+
+// TestReminders tests reminders are announced once
+func TestReminders(t *testing.T) {
+	now := time.Date(2024, 3, 14, 9, 30, 0, 0, time.Local)
+	filename := filepath.Join(t.TempDir(), "todo.json.reminded")
+
+	l := todo.List{}
+	l.Add("Call plumber", todo.WithReminder(now.Add(-time.Hour)))
+	l.Add("Book flights", todo.WithReminder(now.Add(time.Hour)))
+	l.Add("Read book")
+
+	a, err := todo.GetAnnounced(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := l.Reminders(now, a)
+	if len(rs) != 1 || rs[0].Address != "1" {
+		t.Fatalf("Expected reminder of item 1, got %v instead.", rs)
+	}
+
+	a.Announce(&l, rs)
+	if err := a.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err = todo.GetAnnounced(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the second reminder is due two hours later
+	rs = l.Reminders(now.Add(2*time.Hour), a)
+	if len(rs) != 1 || rs[0].Address != "2" {
+		t.Fatalf("Expected reminder of item 2, got %v instead.", rs)
+	}
+
+	// Moving a reminder announces it again
+	l.Edit(1, "Call plumber", todo.WithReminder(now.Add(time.Hour)))
+	if rs = l.Reminders(now.Add(2*time.Hour), a); len(rs) != 2 {
+		t.Errorf("Expected %d reminders, got %v instead.", 2, rs)
+	}
+
+	// Completed items are not announced and are forgotten
+	l.Complete(1)
+	l.Complete(2)
+	if rs = l.Reminders(now.Add(2*time.Hour), a); len(rs) != 0 {
+		t.Errorf("Expected no reminders, got %v instead.", rs)
+	}
+
+	a.Announce(&l, nil)
+	if len(a) != 0 {
+		t.Errorf("Expected no announced reminders, got %v instead.", a)
+	}
+}
+
+// TestRecurringReminder tests the next instance of a recurring item
+// is reminded as long before its due date as the completed one
+func TestRecurringReminder(t *testing.T) {
+	y, m, d := time.Now().AddDate(0, 0, 1).Date()
+	due := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	remind := time.Date(y, m, d-1, 17, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Take out bins", todo.WithDue(due), todo.WithRecurrence("weekly"), todo.WithReminder(remind))
+	l.Complete(1)
+
+	expected := remind.AddDate(0, 0, 7)
+	if !l[1].Remind.Equal(expected) {
+		t.Errorf("Expected reminder at %s, got %s instead.", expected, l[1].Remind)
+	}
+}
@@ -0,0 +1,288 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage backends supported by NewStore
+const (
+	StoreJSON = "json"
+	StoreTxt  = "txt"
+	StoreLog  = "log"
+)
+
+// Store persists a List
+type Store interface {
+	// Load replaces the contents of l with the stored list
+	Load(l *List) error
+	// Save stores the contents of l
+	Save(l *List) error
+}
+
+// NewStore returns the Store of the given kind for filename.
+// When kind is empty the backend is chosen by the file extension:
+// .txt for todo.txt, .log for the append log and JSON otherwise
+func NewStore(filename, kind string) (Store, error) {
+	if kind == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".txt":
+			kind = StoreTxt
+		case ".log":
+			kind = StoreLog
+		default:
+			kind = StoreJSON
+		}
+	}
+
+	switch kind {
+	case StoreJSON:
+		return &JSONStore{Filename: filename}, nil
+	case StoreTxt:
+		return &TxtStore{Filename: filename}, nil
+	case StoreLog:
+		return &LogStore{Filename: filename}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown store %q", kind)
+}
+
+// JSONStore keeps the List as a JSON document, encrypted
+// when Encrypt is set
+type JSONStore struct {
+	Filename string
+	Encrypt  bool
+}
+
+// Load reads the list from the JSON file. Loading an encrypted
+// file sets Encrypt, so saving the list keeps it encrypted
+func (s *JSONStore) Load(l *List) error {
+	*l = List{}
+
+	encrypted, err := l.get(s.Filename)
+	if encrypted {
+		s.Encrypt = true
+	}
+
+	return err
+}
+
+// Save writes the list to the JSON file
+func (s *JSONStore) Save(l *List) error {
+	return l.save(s.Filename, s.Encrypt)
+}
+
+// TxtStore keeps the List in the todo.txt format so other
+// tools can read it. todo.txt only stores dates and one line
+// per item, so the time of creation and completion, the notes,
+// the work sessions, the dependencies and the reminders are
+// not preserved
+type TxtStore struct {
+	Filename string
+}
+
+// Load reads the list from the todo.txt file
+func (s *TxtStore) Load(l *List) error {
+	f, err := os.Open(s.Filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			*l = List{}
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	ls, err := decodeTxt(f)
+	if err != nil {
+		return err
+	}
+
+	*l = ls
+	return nil
+}
+
+// Save writes the list to the todo.txt file
+func (s *TxtStore) Save(l *List) error {
+	var buf bytes.Buffer
+	if err := encodeTxt(&buf, *l, true); err != nil {
+		return err
+	}
+
+	return writeFile(s.Filename, buf.Bytes(), 0644)
+}
+
+// LogStore keeps the List in an append-only log of key-value
+// records keyed by item position. Save only appends the records
+// for positions that changed and compacts the log as it grows
+type LogStore struct {
+	Filename string
+}
+
+// Log record operations
+const (
+	logSet      = "set"
+	logTruncate = "truncate"
+)
+
+// logRecord is a single line of the append log. A set record stores
+// Item at position Index and a truncate record drops every item from
+// position Index onwards
+type logRecord struct {
+	Op    string
+	Index int
+	Item  *item `json:",omitempty"`
+}
+
+// Load replays the log to rebuild the list
+func (s *LogStore) Load(l *List) error {
+	ls, _, _, err := s.replay()
+	if err != nil {
+		return err
+	}
+
+	*l = ls
+	return nil
+}
+
+// Save appends the records needed to turn the stored list into l
+func (s *LogStore) Save(l *List) error {
+	old, n, size, err := s.replay()
+	if err != nil {
+		return err
+	}
+
+	// Rewrite the log with one record per item once it holds
+	// too many stale records
+	if n > 2*len(*l)+100 {
+		return s.compact(*l)
+	}
+
+	var records []logRecord
+	for k, t := range *l {
+		if k < len(old) && sameItem(old[k], t) {
+			continue
+		}
+		records = append(records, logRecord{Op: logSet, Index: k, Item: copyItem(t)})
+	}
+
+	if len(old) > len(*l) {
+		records = append(records, logRecord{Op: logTruncate, Index: len(*l)})
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	data, err := encodeLog(records)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.Filename, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Drop an incomplete last record so the new ones start on a line
+	// of their own
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := f.WriteAt(data, size); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// compact atomically replaces the log with a snapshot of l
+func (s *LogStore) compact(l List) error {
+	records := make([]logRecord, 0, len(l))
+	for k, t := range l {
+		records = append(records, logRecord{Op: logSet, Index: k, Item: copyItem(t)})
+	}
+
+	data, err := encodeLog(records)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(s.Filename, data, 0644)
+}
+
+// replay reads the log and returns the resulting list, the number
+// of records read and the size of the complete records
+func (s *LogStore) replay() (List, int, int64, error) {
+	data, err := os.ReadFile(s.Filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return List{}, 0, 0, nil
+		}
+		return nil, 0, 0, err
+	}
+
+	// A crash while appending can leave an incomplete last line
+	// which is ignored as that record was never fully saved
+	if k := bytes.LastIndexByte(data, '\n'); k != len(data)-1 {
+		data = data[:k+1]
+	}
+
+	l := List{}
+	n := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		n++
+
+		var r logRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, 0, 0, fmt.Errorf("Invalid log record %d: %w", n, err)
+		}
+
+		switch {
+		case r.Op == logSet && r.Item != nil && r.Index >= 0 && r.Index < len(l):
+			l[r.Index] = *r.Item
+		case r.Op == logSet && r.Item != nil && r.Index == len(l):
+			l = append(l, *r.Item)
+		case r.Op == logTruncate && r.Index >= 0 && r.Index <= len(l):
+			l = l[:r.Index]
+		default:
+			return nil, 0, 0, fmt.Errorf("Invalid log record %d", n)
+		}
+	}
+
+	return l, n, int64(len(data)), sc.Err()
+}
+
+// encodeLog encodes records as JSON lines
+func encodeLog(records []logRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
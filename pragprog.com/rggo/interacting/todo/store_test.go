@@ -0,0 +1,114 @@
+package todo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestStores tests saving and loading a list with each storage backend
+func TestStores(t *testing.T) {
+	testCases := []struct {
+		name string
+		file string
+		kind string
+	}{
+		{"JSONByExtension", "todo.json", ""},
+		{"TxtByExtension", "todo.txt", ""},
+		{"LogByExtension", "todo.log", ""},
+		{"LogByKind", "todo.db", todo.StoreLog},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := todo.NewStore(filepath.Join(t.TempDir(), tc.file), tc.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l1 := todo.List{}
+			l1.Add("Task 1")
+			l1.Add("Task 2 +project @context")
+			l1.Add("Task 3")
+			l1.Complete(1)
+
+			if err := s.Save(&l1); err != nil {
+				t.Fatal(err)
+			}
+
+			// Save again after changes to exercise incremental saves
+			l1.Delete(3)
+			l1.Edit(2, "Task 2 edited")
+			if err := s.Save(&l1); err != nil {
+				t.Fatal(err)
+			}
+
+			l2 := todo.List{}
+			if err := s.Load(&l2); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(l2) != len(l1) {
+				t.Fatalf("Expected %d items, got %d instead.", len(l1), len(l2))
+			}
+
+			for k := range l1 {
+				if l1[k].ID != l2[k].ID || l1[k].Task != l2[k].Task || l1[k].Done != l2[k].Done {
+					t.Errorf("Expected %v, got %v instead.", l1[k], l2[k])
+				}
+			}
+		})
+	}
+}
+
+// TestLogStoreIncompleteRecord tests that a partially written
+// record at the end of the log is ignored, and dropped when the
+// list is saved again
+func TestLogStoreIncompleteRecord(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.log")
+	s := &todo.LogStore{Filename: filename}
+
+	l := todo.List{}
+	l.Add("Task 1")
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"Op":"set","Index":1,"It`)
+	f.Close()
+
+	if err := s.Load(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 1 {
+		t.Errorf("Expected %d items, got %d instead.", 1, len(l))
+	}
+
+	l.Add("Task 2")
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	l2 := todo.List{}
+	if err := s.Load(&l2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l2) != 2 || l2[1].Task != "Task 2" {
+		t.Errorf("Expected %d items, got %+v instead.", 2, l2)
+	}
+}
+
+// TestNewStoreUnknown tests that unknown backends are rejected
+func TestNewStoreUnknown(t *testing.T) {
+	if _, err := todo.NewStore("todo.json", "xml"); err == nil {
+		t.Errorf("Expected error for unknown store")
+	}
+}
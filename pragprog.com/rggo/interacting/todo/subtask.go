@@ -0,0 +1,261 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseAddress parses the address of an item, such as "3" for the
+// third item of the list or "3.2" for the second subtask of the
+// third item, into its item numbers
+func ParseAddress(addr string) ([]int, error) {
+	var path []int
+
+	for _, f := range strings.Split(strings.TrimSpace(addr), ".") {
+		n, err := strconv.Atoi(f)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("Invalid item %q", addr)
+		}
+		path = append(path, n)
+	}
+
+	return path, nil
+}
+
+// FormatAddress returns the address of the item at path
+func FormatAddress(path []int) string {
+	s := make([]string, len(path))
+	for k, n := range path {
+		s[k] = strconv.Itoa(n)
+	}
+
+	return strings.Join(s, ".")
+}
+
+// at returns the list holding the item at path
+// along with the 0 based index of the item in it
+func (l *List) at(path []int) (*List, int, error) {
+	if len(path) == 0 {
+		return nil, 0, fmt.Errorf("Item address cannot be blank")
+	}
+
+	ls := l
+	for k, n := range path {
+		if n <= 0 || n > len(*ls) {
+			return nil, 0, fmt.Errorf("Item %s does not exist", FormatAddress(path))
+		}
+
+		if k == len(path)-1 {
+			break
+		}
+		ls = &(*ls)[n-1].Children
+	}
+
+	return ls, path[len(path)-1] - 1, nil
+}
+
+// AddAt creates a new todo item as the last subtask of the item
+// at parent. An empty parent adds it to the list itself
+func (l *List) AddAt(parent []int, task string, opts ...Option) error {
+	if len(parent) == 0 {
+		l.Add(task, opts...)
+		return nil
+	}
+
+	ls, k, err := l.at(parent)
+	if err != nil {
+		return err
+	}
+
+	(*ls)[k].Children.Add(task, opts...)
+	return nil
+}
+
+// CompleteAt marks the item at path as completed. When parents
+// is set, parents whose subtasks are all done are completed
+// as well
+func (l *List) CompleteAt(path []int, parents bool) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	if err := ls.complete(k); err != nil {
+		return err
+	}
+
+	if !parents {
+		return nil
+	}
+
+	for d := len(path) - 1; d > 0; d-- {
+		ls, k, _ := l.at(path[:d])
+
+		t := (*ls)[k]
+		if t.Done || t.Children.pending() > 0 {
+			break
+		}
+
+		if err := ls.complete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// complete marks the item at index k as completed and appends
+// the next instance of recurring items
+func (l *List) complete(k int) error {
+	t := &(*l)[k]
+
+	var next *item
+	if t.Recur != "" && !t.Done {
+		r, err := ParseRecurrence(t.Recur)
+		if err != nil {
+			return err
+		}
+		next = t.next(r, time.Now())
+	}
+
+	t.Done = true
+	t.CompletedAt = time.Now()
+	t.stop(t.CompletedAt)
+
+	if next != nil {
+		*l = append(*l, *next)
+	}
+
+	return nil
+}
+
+// ReopenAt marks the completed item at path as pending again
+func (l *List) ReopenAt(path []int) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	(*ls)[k].Done = false
+	(*ls)[k].CompletedAt = time.Time{}
+
+	return nil
+}
+
+// DeleteAt deletes the item at path along with its subtasks
+func (l *List) DeleteAt(path []int) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	*ls = append((*ls)[:k], (*ls)[k+1:]...)
+	return nil
+}
+
+// EditAt replaces the description of the item at
+// path and applies any options provided
+func (l *List) EditAt(path []int, task string, opts ...Option) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	(*ls)[k].Task = task
+	for _, opt := range opts {
+		opt(&(*ls)[k])
+	}
+
+	return nil
+}
+
+// pending returns the number of items not completed yet
+func (l List) pending() int {
+	n := 0
+	for _, t := range l {
+		if !t.Done {
+			n++
+		}
+	}
+
+	return n
+}
+
+// clone returns a deep copy of the item, so later changes
+// to its subtasks don't affect the copy
+func (t item) clone() item {
+	if t.Children != nil {
+		children := make(List, len(t.Children))
+		for k, c := range t.Children {
+			children[k] = c.clone()
+		}
+		t.Children = children
+	}
+
+	t.Sessions = append([]Session(nil), t.Sessions...)
+	t.Deps = append([]string(nil), t.Deps...)
+
+	return t
+}
+
+// nester rebuilds nested lists from items read one per line,
+// using the indentation of each line to find its parent
+type nester struct {
+	stack []level
+}
+
+// level is an item that can take subtasks and its indentation
+type level struct {
+	indent   int
+	children *List
+}
+
+// newNester returns a nester that adds top level items to l
+func newNester(l *List) *nester {
+	return &nester{stack: []level{{indent: -1, children: l}}}
+}
+
+// add adds t below the closest previous item with less indentation
+func (n *nester) add(indent int, t item) {
+	if t.ID == "" {
+		t.ID = newID()
+	}
+
+	for len(n.stack) > 1 && n.stack[len(n.stack)-1].indent >= indent {
+		n.stack = n.stack[:len(n.stack)-1]
+	}
+
+	ls := n.stack[len(n.stack)-1].children
+	*ls = append(*ls, t)
+	n.stack = append(n.stack, level{indent: indent, children: &(*ls)[len(*ls)-1].Children})
+}
+
+// indentation returns the width of the leading
+// whitespace of line, counting tabs as 4 spaces
+func indentation(line string) int {
+	w := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			w++
+		case '\t':
+			w += 4
+		default:
+			return w
+		}
+	}
+
+	return w
+}
+
+// walk calls fn for every item of the list and its subtasks,
+// parents first, along with the path to the item
+func (l *List) walk(parent []int, fn func(path []int, t item)) {
+	for k, t := range *l {
+		path := append(append([]int{}, parent...), k+1)
+		fn(path, t)
+		t.Children.walk(path, fn)
+	}
+}
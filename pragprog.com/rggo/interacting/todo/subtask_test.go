@@ -0,0 +1,152 @@
+package todo_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// newNestedList returns a list with a parent item holding two subtasks
+// and a nested subtask
+func newNestedList(t *testing.T) todo.List {
+	t.Helper()
+
+	l := todo.List{}
+	l.Add("Release")
+	l.Add("Other task")
+
+	for _, step := range []struct {
+		parent string
+		task   string
+	}{
+		{"1", "Write docs"},
+		{"1", "Tag version"},
+		{"1.2", "Sign tag"},
+	} {
+		path, err := todo.ParseAddress(step.parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.AddAt(path, step.task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return l
+}
+
+// TestParseAddress tests parsing item addresses
+func TestParseAddress(t *testing.T) {
+	path, err := todo.ParseAddress("3.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []int{3, 2, 1}; !reflect.DeepEqual(path, exp) {
+		t.Errorf("Expected %v, got %v instead.", exp, path)
+	}
+
+	if todo.FormatAddress(path) != "3.2.1" {
+		t.Errorf("Expected %q, got %q instead.", "3.2.1", todo.FormatAddress(path))
+	}
+
+	for _, addr := range []string{"", "3.", "a.1", "0", "1.-2"} {
+		if _, err := todo.ParseAddress(addr); err == nil {
+			t.Errorf("Expected error for address %q", addr)
+		}
+	}
+}
+
+// TestSubtasks tests rendering and completing nested items
+func TestSubtasks(t *testing.T) {
+	l := newNestedList(t)
+
+	exp := "  1: Release (0/2)\n    1.1: Write docs\n    1.2: Tag version (0/1)\n" +
+		"      1.2.1: Sign tag\n  2: Other task\n"
+	if l.String() != exp {
+		t.Errorf("Expected %q, got %q instead.", exp, l.String())
+	}
+
+	if err := l.CompleteAt([]int{1, 1}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.CompleteAt([]int{1, 2, 1}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Completing the last subtasks completes every parent up the tree
+	exp = "X 1: Release (2/2)\n  X 1.1: Write docs\n  X 1.2: Tag version (1/1)\n" +
+		"    X 1.2.1: Sign tag\n  2: Other task\n"
+	if l.String() != exp {
+		t.Errorf("Expected %q, got %q instead.", exp, l.String())
+	}
+
+	if exp := "  2: Other task\n"; l.Pend() != exp {
+		t.Errorf("Expected %q, got %q instead.", exp, l.Pend())
+	}
+
+	if err := l.DeleteAt([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(l[0].Children) != 1 {
+		t.Errorf("Expected %d subtasks, got %d instead.", 1, len(l[0].Children))
+	}
+
+	if err := l.CompleteAt([]int{1, 5}, true); err == nil {
+		t.Errorf("Expected error completing a missing subtask")
+	}
+}
+
+// TestSubtasksNoAutoComplete tests leaving parents open
+func TestSubtasksNoAutoComplete(t *testing.T) {
+	l := newNestedList(t)
+	l.CompleteAt([]int{1, 1}, false)
+	l.CompleteAt([]int{1, 2, 1}, false)
+
+	if l[0].Done || l[0].Children[1].Done {
+		t.Errorf("Expected parents to stay pending")
+	}
+}
+
+// TestSubtasksJournal tests undoing changes to subtasks
+func TestSubtasksJournal(t *testing.T) {
+	l := newNestedList(t)
+	j := todo.NewJournal(filepath.Join(t.TempDir(), "todo.json"))
+
+	if err := j.CompleteAt(&l, []int{1, 2, 1}, true); err != nil {
+		t.Fatal(err)
+	}
+	if !l[0].Children[1].Done {
+		t.Fatalf("Expected parent subtask to be completed")
+	}
+
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if l[0].Children[1].Done || l[0].Children[1].Children[0].Done {
+		t.Errorf("Expected subtasks to be pending after undo")
+	}
+}
+
+// TestSubtasksTxt tests keeping subtasks in todo.txt files
+func TestSubtasksTxt(t *testing.T) {
+	l := newNestedList(t)
+
+	var out bytes.Buffer
+	if err := l.ExportTxt(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	l2, err := todo.ImportTxt(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.String() != l2.String() {
+		t.Errorf("Expected %q, got %q instead.", l.String(), l2.String())
+	}
+}
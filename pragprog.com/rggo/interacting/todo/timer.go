@@ -0,0 +1,214 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Session is an interval of work on an item. End is zero
+// while the session is running
+type Session struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Spent returns the time worked on the item, counting
+// running sessions up to now
+func (t item) Spent(now time.Time) time.Duration {
+	var d time.Duration
+	for _, s := range t.Sessions {
+		d += s.duration(now)
+	}
+
+	return d
+}
+
+// Running reports whether a session is running on the item
+func (t item) Running() bool {
+	n := len(t.Sessions)
+	return n > 0 && t.Sessions[n-1].End.IsZero()
+}
+
+// duration returns the length of the session, up to now if it's running
+func (s Session) duration(now time.Time) time.Duration {
+	if s.End.IsZero() {
+		return now.Sub(s.Start)
+	}
+
+	return s.End.Sub(s.Start)
+}
+
+// StartAt starts a work session on the item at path
+func (l *List) StartAt(path []int, now time.Time) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	t := &(*ls)[k]
+	if t.Done {
+		return fmt.Errorf("Item %s is completed", FormatAddress(path))
+	}
+	if t.Running() {
+		return fmt.Errorf("Item %s is already running", FormatAddress(path))
+	}
+
+	t.Sessions = append(t.Sessions, Session{Start: now})
+	return nil
+}
+
+// StopAt stops the work session running on the item at path
+func (l *List) StopAt(path []int, now time.Time) error {
+	ls, k, err := l.at(path)
+	if err != nil {
+		return err
+	}
+
+	t := &(*ls)[k]
+	if !t.Running() {
+		return fmt.Errorf("Item %s is not running", FormatAddress(path))
+	}
+
+	t.stop(now)
+	return nil
+}
+
+// stop ends the running session of the item, if any
+func (t *item) stop(now time.Time) {
+	if t.Running() {
+		t.Sessions[len(t.Sessions)-1].End = now
+	}
+}
+
+// StartAt starts a work session on the item at path and records the operation
+func (j *Journal) StartAt(l *List, path []int, now time.Time) error {
+	return j.updateAt(OpStart, l, path, func() error { return l.StartAt(path, now) })
+}
+
+// StopAt stops the session running on the item at path and records the operation
+func (j *Journal) StopAt(l *List, path []int, now time.Time) error {
+	return j.updateAt(OpStop, l, path, func() error { return l.StopAt(path, now) })
+}
+
+// Spent is the time worked on a task, tag or day
+type Spent struct {
+	Name  string        `json:"name"`
+	Spent time.Duration `json:"spent_ns"`
+}
+
+// TimeReport summarizes the time worked on the items of a list
+type TimeReport struct {
+	Total time.Duration `json:"total_ns"`
+	Tasks []Spent       `json:"tasks"`
+	Tags  []Spent       `json:"tags"`
+	Days  []Spent       `json:"days"`
+}
+
+// TimeReport computes the time worked per item, including subtasks,
+// per +project and @context tag and per day. Running sessions count
+// up to now, and sessions spanning midnight are split between days
+func (l *List) TimeReport(now time.Time) TimeReport {
+	return l.TimeReportWith(nil, now)
+}
+
+// TimeReportWith computes the time report of the list along with
+// the items of its archive, which are named "archived N"
+func (l *List) TimeReportWith(archive List, now time.Time) TimeReport {
+	var r TimeReport
+	tags := map[string]time.Duration{}
+	days := map[string]time.Duration{}
+
+	add := func(name string, t item) {
+		spent := t.Spent(now)
+		if spent == 0 {
+			return
+		}
+
+		r.Total += spent
+		r.Tasks = append(r.Tasks, Spent{
+			Name:  fmt.Sprintf("%s: %s", name, t.Task),
+			Spent: spent,
+		})
+
+		for _, tag := range t.Tags() {
+			tags[tag] += spent
+		}
+
+		for _, s := range t.Sessions {
+			end := now
+			if !s.End.IsZero() {
+				end = s.End
+			}
+
+			for start := s.Start; start.Before(end); {
+				next := day(start).AddDate(0, 0, 1)
+				if next.After(end) {
+					next = end
+				}
+				days[start.Format("2006-01-02")] += next.Sub(start)
+				start = next
+			}
+		}
+	}
+
+	l.walk(nil, func(path []int, t item) {
+		add(FormatAddress(path), t)
+	})
+	archive.walk(nil, func(path []int, t item) {
+		add("archived "+FormatAddress(path), t)
+	})
+
+	// Tasks with the most time first, days in order
+	sort.SliceStable(r.Tasks, func(a, b int) bool { return r.Tasks[a].Spent > r.Tasks[b].Spent })
+	r.Tags = sortedSpent(tags)
+	sort.SliceStable(r.Tags, func(a, b int) bool { return r.Tags[a].Spent > r.Tags[b].Spent })
+	r.Days = sortedSpent(days)
+
+	return r
+}
+
+// sortedSpent returns the times in the map sorted by name
+func sortedSpent(m map[string]time.Duration) []Spent {
+	s := make([]Spent, 0, len(m))
+	for name, d := range m {
+		s = append(s, Spent{Name: name, Spent: d})
+	}
+	sort.Slice(s, func(a, b int) bool { return s[a].Name < s[b].Name })
+
+	return s
+}
+
+// String prints out the time report
+func (r TimeReport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Total: %s\n", formatClock(r.Total))
+
+	for _, section := range []struct {
+		title string
+		spent []Spent
+	}{
+		{"Per task", r.Tasks},
+		{"Per tag", r.Tags},
+		{"Per day", r.Days},
+	} {
+		if len(section.spent) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, s := range section.spent {
+			fmt.Fprintf(&b, "  %8s  %s\n", formatClock(s.Spent), s.Name)
+		}
+	}
+
+	return b.String()
+}
+
+// formatClock prints out a duration in hours and minutes
+func formatClock(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,154 @@
+package todo_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestStartStop tests recording work sessions on an item
+func TestStartStop(t *testing.T) {
+	start := time.Date(2024, 3, 14, 9, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Write report")
+
+	if err := l.StartAt([]int{1}, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.StartAt([]int{1}, start); err == nil {
+		t.Error("Expected error starting a running item")
+	}
+	if !l[0].Running() {
+		t.Error("Expected item to be running")
+	}
+
+	if d := l[0].Spent(start.Add(30 * time.Minute)); d != 30*time.Minute {
+		t.Errorf("Expected %s spent while running, got %s instead.", 30*time.Minute, d)
+	}
+
+	if err := l.StopAt([]int{1}, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.StopAt([]int{1}, start.Add(time.Hour)); err == nil {
+		t.Error("Expected error stopping an item not running")
+	}
+
+	// A second session adds to the first one
+	l.StartAt([]int{1}, start.Add(2*time.Hour))
+	l.StopAt([]int{1}, start.Add(2*time.Hour+15*time.Minute))
+
+	if d := l[0].Spent(time.Now()); d != 75*time.Minute {
+		t.Errorf("Expected %s spent, got %s instead.", 75*time.Minute, d)
+	}
+	if len(l[0].Sessions) != 2 {
+		t.Errorf("Expected %d sessions, got %d instead.", 2, len(l[0].Sessions))
+	}
+}
+
+// TestCompleteStopsTimer tests completing an item stops its session
+func TestCompleteStopsTimer(t *testing.T) {
+	l := todo.List{}
+	l.Add("Write report")
+
+	l.StartAt([]int{1}, time.Now().Add(-time.Minute))
+	l.Complete(1)
+
+	if l[0].Running() {
+		t.Error("Expected session to stop when the item is completed")
+	}
+	if err := l.StartAt([]int{1}, time.Now()); err == nil {
+		t.Error("Expected error starting a completed item")
+	}
+}
+
+// TestTimerJournal tests undoing a stopped session restores it running
+func TestTimerJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	start := time.Now().Add(-time.Hour)
+
+	l := todo.List{}
+	j := todo.NewJournal(filename)
+	j.Add(&l, "Write report")
+
+	if err := j.StartAt(&l, []int{1}, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.StopAt(&l, []int{1}, start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := j.Undo(&l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Op != todo.OpStop || !l[0].Running() {
+		t.Errorf("Expected stop undone, got %s with running %t instead.", e.Op, l[0].Running())
+	}
+}
+
+// TestTimeReport tests the time worked per task, tag and day
+func TestTimeReport(t *testing.T) {
+	day := time.Date(2024, 3, 14, 0, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Fix login +web @work")
+	l.Add("Deploy +web")
+	l.Add("Not tracked")
+
+	// Two hours across midnight, and one running since 9:00
+	l.StartAt([]int{1}, day.Add(-time.Hour))
+	l.StopAt([]int{1}, day.Add(time.Hour))
+	l.StartAt([]int{2}, day.Add(9*time.Hour))
+
+	r := l.TimeReport(day.Add(9*time.Hour + 30*time.Minute))
+
+	if r.Total != 150*time.Minute {
+		t.Errorf("Expected total %s, got %s instead.", 150*time.Minute, r.Total)
+	}
+
+	if len(r.Tasks) != 2 || r.Tasks[0].Name != "1: Fix login +web @work" || r.Tasks[1].Spent != 30*time.Minute {
+		t.Errorf("Unexpected tasks %+v", r.Tasks)
+	}
+
+	if len(r.Tags) != 2 || r.Tags[0].Name != "+web" || r.Tags[0].Spent != 150*time.Minute {
+		t.Errorf("Unexpected tags %+v", r.Tags)
+	}
+
+	expDays := []todo.Spent{{"2024-03-13", time.Hour}, {"2024-03-14", 90 * time.Minute}}
+	if len(r.Days) != 2 || r.Days[0] != expDays[0] || r.Days[1] != expDays[1] {
+		t.Errorf("Expected days %+v, got %+v instead.", expDays, r.Days)
+	}
+
+	if s := r.String(); !strings.Contains(s, "Total: 2h30m") || !strings.Contains(s, "1h30m  2024-03-14") {
+		t.Errorf("Unexpected report:\n%s", s)
+	}
+
+	// Time worked on archived tasks counts too
+	archive := todo.List{}
+	archive.Add("Old release +web")
+	archive.StartAt([]int{1}, day.Add(2*time.Hour))
+	archive.StopAt([]int{1}, day.Add(3*time.Hour))
+	archive.Complete(1)
+
+	r = l.TimeReportWith(archive, day.Add(9*time.Hour+30*time.Minute))
+	if r.Total != 210*time.Minute || r.Tasks[1].Name != "archived 1: Old release +web" || r.Tags[0].Spent != 210*time.Minute {
+		t.Errorf("Unexpected report with the archive %+v", r)
+	}
+}
+
+// TestRunningTimerListing tests listings show running sessions
+func TestRunningTimerListing(t *testing.T) {
+	l := todo.List{}
+	l.Add("Write report")
+	l.Add("Review")
+	l.StartAt([]int{1}, time.Now().Add(-65*time.Minute))
+
+	expected := "  1: Write report [running, 1h05m]\n  2: Review\n"
+	if l.String() != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, l.String())
+	}
+}
@@ -1,19 +1,53 @@
 package todo
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
-	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
 // item struct represents a ToDo item
 type item struct {
+	ID          string `json:",omitempty"`
 	Task        string
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Priority    string `json:",omitempty"`
+	Due         time.Time
+	Recur       string    `json:",omitempty"`
+	Notes       string    `json:",omitempty"`
+	Children    List      `json:",omitempty"`
+	Sessions    []Session `json:",omitempty"`
+	Deps        []string  `json:",omitempty"`
+	Remind      time.Time
+}
+
+// Option sets an optional attribute of an item
+type Option func(*item)
+
+// WithDue sets the date the item is due
+func WithDue(due time.Time) Option {
+	return func(t *item) {
+		t.Due = due
+	}
+}
+
+// WithRecurrence sets the rule used to repeat the item when it is
+// completed. Validate the rule with ParseRecurrence beforehand
+func WithRecurrence(rule string) Option {
+	return func(t *item) {
+		t.Recur = rule
+	}
+}
+
+// WithNotes sets free-form notes on the item
+func WithNotes(notes string) Option {
+	return func(t *item) {
+		t.Notes = notes
+	}
 }
 
 // List represents a list of ToDo items
@@ -22,114 +56,139 @@ type List []item
 // String prints out a formatted list
 // Implements the fmt.Stringer interface
 func (l *List) String() string {
-	formatted := ""
-
-	for k, t := range *l {
-		prefix := "  "
-		if t.Done {
-			prefix = "X "
-		}
-
-		// Adjust the item number k to print numbers starting from 1 instead of 0
-		formatted += fmt.Sprintf("%s%d: %s\n", prefix, k+1, t.Task)
-	}
-	return formatted
+	return l.render(false, PlainFormatter{})
 }
 
+// Verbose prints out the list with the creation date and
+// the schedule of each item
 func (l *List) Verbose() string {
-	formatted := ""
-
-	for k, t := range *l {
-		prefix := "  "
-		if t.Done {
-			prefix = "X "
-		}
-
-		// Adjust the item number k to print numbers starting from 1 instead of 0
-		formatted += fmt.Sprintf("%s%d: %s	%s\n", prefix, k+1, t.Task, t.CreatedAt.Format("2006-01-02 15:04"))
-	}
-	return formatted
+	return l.render(false, PlainFormatter{Verbose: true})
 }
 
+// Pend prints out the pending items of the list
 func (l *List) Pend() string {
-	formatted := ""
+	return l.render(true, PlainFormatter{})
+}
 
-	for k, t := range *l {
-		prefix := "  "
-		if !t.Done {
-			// Adjust the item number k to print numbers starting from 1 instead of 0
-			formatted += fmt.Sprintf("%s%d: %s\n", prefix, k+1, t.Task)
-		}
+// render formats the rows of the list with f
+func (l *List) render(pending bool, f Formatter) string {
+	var b strings.Builder
+	f.Format(&b, l.Rows(pending))
 
-	}
-	return formatted
+	return b.String()
 }
 
 // Add creates a new todo item and appends it to the list
-func (l *List) Add(task string) {
+func (l *List) Add(task string, opts ...Option) {
 	t := item{
+		ID:          newID(),
 		Task:        task,
 		Done:        false,
 		CreatedAt:   time.Now(),
 		CompletedAt: time.Time{},
 	}
 
+	for _, opt := range opts {
+		opt(&t)
+	}
+
 	*l = append(*l, t)
 }
 
 // Complete method marks a ToDo item as completed by
-// setting Done = true and CompletedAt to the current time
+// setting Done = true and CompletedAt to the current time.
+// Completing a recurring item appends its next pending instance
 func (l *List) Complete(i int) error {
-	ls := *l
-	if i <= 0 || i > len(ls) {
-		return fmt.Errorf("Item %d does not exist", i)
-	}
-
-	// Adjusting index for 0 based index
-	ls[i-1].Done = true
-	ls[i-1].CompletedAt = time.Now()
+	return l.CompleteAt([]int{i}, true)
+}
 
-	return nil
+// Reopen method marks a completed ToDo item as pending again
+func (l *List) Reopen(i int) error {
+	return l.ReopenAt([]int{i})
 }
 
 // Delete method deletes a ToDo item from the list
 func (l *List) Delete(i int) error {
-	ls := *l
-	if i <= 0 || i > len(ls) {
-		return fmt.Errorf("Item %d does not exist", i)
+	return l.DeleteAt([]int{i})
+}
+
+// Edit method replaces the description of a ToDo item
+// and applies any options provided
+func (l *List) Edit(i int, task string, opts ...Option) error {
+	return l.EditAt([]int{i}, task, opts...)
+}
+
+// Save method encodes the List as Json, wrapped in an envelope
+// with the schema version, and saves it using the provided file
+// name. The file is replaced atomically so a crash never leaves
+// it truncated. Files already encrypted are encrypted again, so a
+// list read with Get is saved the way it was
+func (l *List) Save(filename string) error {
+	encrypted, err := IsEncrypted(filename)
+	if err != nil {
+		return err
 	}
 
-	// Adjust index for 0 based index
-	*l = append(ls[:i-1], ls[i:]...)
+	return l.save(filename, encrypted)
+}
 
-	return nil
+// SaveEncrypted method saves the List like Save, encrypted with
+// the key derived from Passphrase
+func (l *List) SaveEncrypted(filename string) error {
+	return l.save(filename, true)
 }
 
-// Save method encodes the List as Json and saves it
-// using the provided file name
-func (l *List) Save(filename string) error {
-	js, err := json.Marshal(l)
+// save encodes the List and writes it, encrypted or not
+func (l *List) save(filename string, encrypted bool) error {
+	js, err := encodeList(*l)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, js, 0644)
+	if encrypted {
+		if js, err = encrypt(js); err != nil {
+			return err
+		}
+	}
+
+	return writeFile(filename, js, 0644)
 }
 
 // Get method opens the provided file name, decodes
-// the JSON data and parses it into a List
+// the JSON data and parses it into a List. Encrypted files are
+// decrypted and files written with older schema versions are
+// migrated as they're read
 func (l *List) Get(filename string) error {
+	_, err := l.get(filename)
+	return err
+}
+
+// get reads the List like Get and reports whether the
+// file was encrypted
+func (l *List) get(filename string) (bool, error) {
 	file, err := os.ReadFile(filename)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil
+			return false, nil
 		}
-		return err
+		return false, err
 	}
 
 	if len(file) == 0 {
-		return nil
+		return false, nil
+	}
+
+	encrypted := bytes.HasPrefix(file, []byte(encMagic))
+	file, err = decrypt(file)
+	if err != nil {
+		return encrypted, err
+	}
+
+	ls, err := decodeList(file)
+	if err != nil {
+		return encrypted, err
 	}
 
-	return json.Unmarshal(file, l)
+	*l = ls
+	return encrypted, nil
 }
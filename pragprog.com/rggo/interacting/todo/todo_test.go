@@ -92,3 +92,39 @@ func TestSaveGet(t *testing.T) {
 		t.Errorf("Task %q should match %q task.", l1[0].Task, l2[0].Task)
 	}
 }
+
+// TestEdit tests the Edit method of the List type
+func TestEdit(t *testing.T) {
+	l := todo.List{}
+
+	l.Add("New Task")
+
+	newName := "Edited Task"
+	if err := l.Edit(1, newName); err != nil {
+		t.Fatal(err)
+	}
+
+	if l[0].Task != newName {
+		t.Errorf("Expected %q, got %q instead.", newName, l[0].Task)
+	}
+
+	if err := l.Edit(2, newName); err == nil {
+		t.Errorf("Expected error editing a missing item")
+	}
+}
+
+// TestReopen tests the Reopen method of the List type
+func TestReopen(t *testing.T) {
+	l := todo.List{}
+
+	l.Add("New Task")
+	l.Complete(1)
+
+	if err := l.Reopen(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if l[0].Done || !l[0].CompletedAt.IsZero() {
+		t.Errorf("Reopened task should be pending")
+	}
+}
@@ -0,0 +1,409 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// txtDate is the date layout used by the todo.txt format
+const txtDate = "2006-01-02"
+
+// txtTime is the layout of the times kept in remind: and session:
+// extras, which can't contain colons
+const txtTime = "2006-01-02T150405"
+
+// ExportTxt writes the list to w in the todo.txt format. Attributes
+// other tools have no use for, such as IDs, are only kept by TxtStore
+func (l *List) ExportTxt(w io.Writer) error {
+	return encodeTxt(w, *l, false)
+}
+
+// ImportTxt reads a list in the todo.txt format from r
+func ImportTxt(r io.Reader) (List, error) {
+	return decodeTxt(r)
+}
+
+// Projects returns the +project tags found in the task description
+func (t item) Projects() []string {
+	return t.tokens("+")
+}
+
+// Contexts returns the @context tags found in the task description
+func (t item) Contexts() []string {
+	return t.tokens("@")
+}
+
+// Tags returns both the projects and contexts of the item, sorted
+func (t item) Tags() []string {
+	tags := append(t.Projects(), t.Contexts()...)
+	sort.Strings(tags)
+
+	return tags
+}
+
+// Tags returns the projects and contexts used by the items of the
+// list and their subtasks, sorted and without duplicates
+func (l *List) Tags() []string {
+	seen := map[string]bool{}
+	var tags []string
+
+	l.walk(nil, func(path []int, t item) {
+		for _, tag := range t.Tags() {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	})
+	sort.Strings(tags)
+
+	return tags
+}
+
+// Extras returns the key:value pairs found in the task description
+func (t item) Extras() map[string]string {
+	extras := map[string]string{}
+
+	for _, f := range strings.Fields(t.Task) {
+		if k, v, ok := cutExtra(f); ok {
+			extras[k] = v
+		}
+	}
+
+	return extras
+}
+
+// tokens returns the words of the description starting with prefix
+func (t item) tokens(prefix string) []string {
+	var tokens []string
+
+	for _, f := range strings.Fields(t.Task) {
+		if len(f) > len(prefix) && strings.HasPrefix(f, prefix) {
+			tokens = append(tokens, f)
+		}
+	}
+
+	return tokens
+}
+
+// cutExtra splits a key:value word. URLs such as https://example.com
+// are not considered extras
+func cutExtra(f string) (string, string, bool) {
+	k, v, ok := strings.Cut(f, ":")
+	if !ok || k == "" || v == "" || strings.ContainsAny(v, ":") ||
+		strings.HasPrefix(v, "//") {
+		return "", "", false
+	}
+
+	return k, v, true
+}
+
+// encodeTxt writes l to w in the todo.txt format, one item per line.
+// todo.txt has no subtasks, so they follow their parent indented by
+// two spaces per level, which other tools read as regular items.
+// The attributes only this tool uses are written when all is set
+func encodeTxt(w io.Writer, l List, all bool) error {
+	return encodeTxtLevel(w, l, "", all)
+}
+
+// encodeTxtLevel writes the items of l with the given indentation
+func encodeTxtLevel(w io.Writer, l List, indent string, all bool) error {
+	for _, t := range l {
+		if _, err := fmt.Fprintln(w, indent+t.txt(all)); err != nil {
+			return err
+		}
+
+		if err := encodeTxtLevel(w, t.Children, indent+"  ", all); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// txt returns the todo.txt representation of the item: an optional
+// "x" completion marker, the priority, the completion and creation
+// dates and the task description. Completed items keep their
+// priority in a pri:X extra as the format suggests, and the due date
+// and recurrence rule are kept in due: and rec: extras. When all is
+// set, the ID is kept in an id: extra if it can't be derived from the
+// line, followed by dep:, remind:, session: and notes: extras for the
+// dependencies, reminder, work sessions and escaped notes. Items without
+// a creation date get one when the description could be read back as
+// a completion marker, a priority or a date, or when the completion
+// date would be read as the creation date: the completion date, or
+// today for pending items
+func (t item) txt(all bool) string {
+	var fields []string
+
+	created := t.CreatedAt
+	if created.IsZero() && (misread(t.Task) || (t.Done && !t.CompletedAt.IsZero())) {
+		created = t.CompletedAt
+		if created.IsZero() {
+			created = time.Now()
+		}
+	}
+
+	if t.Done {
+		fields = append(fields, "x")
+		if !t.CompletedAt.IsZero() {
+			fields = append(fields, t.CompletedAt.Format(txtDate))
+		}
+	} else if t.Priority != "" {
+		fields = append(fields, "("+t.Priority+")")
+	}
+
+	if !created.IsZero() {
+		fields = append(fields, created.Format(txtDate))
+	}
+
+	fields = append(fields, t.Task)
+
+	if t.Done && t.Priority != "" {
+		fields = append(fields, "pri:"+t.Priority)
+	}
+
+	if !t.Due.IsZero() {
+		fields = append(fields, "due:"+t.Due.Format(txtDate))
+	}
+
+	if t.Recur != "" {
+		fields = append(fields, "rec:"+t.Recur)
+	}
+
+	read := t
+	read.CreatedAt = created
+	if !all {
+		return strings.Join(fields, " ")
+	}
+
+	if t.ID != "" && t.ID != read.txtID(1) {
+		fields = append(fields, "id:"+t.ID)
+	}
+
+	for _, id := range t.Deps {
+		fields = append(fields, "dep:"+id)
+	}
+
+	if !t.Remind.IsZero() {
+		fields = append(fields, "remind:"+t.Remind.Local().Format(txtTime))
+	}
+
+	for _, s := range t.Sessions {
+		end := ""
+		if !s.End.IsZero() {
+			end = s.End.Local().Format(txtTime)
+		}
+		fields = append(fields, "session:"+s.Start.Local().Format(txtTime)+"/"+end)
+	}
+
+	if t.Notes != "" {
+		fields = append(fields, "notes:"+url.QueryEscape(t.Notes))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// misread reports whether a description at the start of a todo.txt
+// line would be read as a completion marker, a priority or a date
+func misread(task string) bool {
+	if strings.HasPrefix(task, "x ") {
+		return true
+	}
+	if _, _, ok := cutPriority(task); ok {
+		return true
+	}
+	_, _, ok := cutDate(task)
+
+	return ok
+}
+
+// txtID returns the ID of an item read without an id: extra, derived
+// from its creation date and task so it's the same every time the
+// line is read. The nth identical line also hashes its number
+func (t item) txtID(n int) string {
+	created := ""
+	if !t.CreatedAt.IsZero() {
+		created = t.CreatedAt.Format(txtDate)
+	}
+	if n > 1 {
+		created += fmt.Sprintf("#%d", n)
+	}
+
+	return contentID(created, t.Task)
+}
+
+// decodeTxt reads a list in the todo.txt format from r.
+// Indented lines are subtasks of the item above them
+func decodeTxt(r io.Reader) (List, error) {
+	l := List{}
+	n := newNester(&l)
+	s := bufio.NewScanner(r)
+	seen := map[string]bool{}
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		t := parseTxt(line)
+		for k := 1; t.ID == "" || seen[t.ID]; k++ {
+			t.ID = t.txtID(k)
+		}
+		seen[t.ID] = true
+
+		n.add(indentation(s.Text()), t)
+	}
+
+	return l, s.Err()
+}
+
+// parseTxt parses a single todo.txt line into an item
+func parseTxt(line string) item {
+	var t item
+
+	if rest, ok := strings.CutPrefix(line, "x "); ok {
+		t.Done = true
+		line = rest
+	}
+
+	if p, rest, ok := cutPriority(line); ok {
+		t.Priority = p
+		line = rest
+	}
+
+	if d, rest, ok := cutDate(line); ok {
+		t.CreatedAt = d
+		line = rest
+
+		// Completed items list the completion date before the creation date
+		if d2, rest, ok := cutDate(line); ok && t.Done {
+			t.CompletedAt, t.CreatedAt = t.CreatedAt, d2
+			line = rest
+		}
+	}
+
+	var fields []string
+	found := false
+	for _, f := range strings.Fields(line) {
+		if t.setExtra(f) {
+			found = true
+			continue
+		}
+		fields = append(fields, f)
+	}
+	if found {
+		line = strings.Join(fields, " ")
+	}
+
+	t.Task = line
+	return t
+}
+
+// setExtra sets the attribute stored in a pri:, due:, rec:, id:, dep:,
+// remind:, session: or notes: extra
+// and reports whether f was one of them
+func (t *item) setExtra(f string) bool {
+	k, v, ok := cutExtra(f)
+	if !ok {
+		return false
+	}
+
+	switch k {
+	case "pri":
+		if t.Done && isPriority(v) {
+			t.Priority = v
+			return true
+		}
+	case "due":
+		if d, err := time.ParseInLocation(txtDate, v, time.Local); err == nil {
+			t.Due = d
+			return true
+		}
+	case "rec":
+		if _, err := ParseRecurrence(v); err == nil {
+			t.Recur = v
+			return true
+		}
+	case "id":
+		t.ID = v
+		return true
+	case "dep":
+		t.Deps = append(t.Deps, v)
+		return true
+	case "remind":
+		if d, err := time.ParseInLocation(txtTime, v, time.Local); err == nil {
+			t.Remind = d
+			return true
+		}
+	case "session":
+		if s, ok := parseSession(v); ok {
+			t.Sessions = append(t.Sessions, s)
+			return true
+		}
+	case "notes":
+		if notes, err := url.QueryUnescape(v); err == nil {
+			t.Notes = notes
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSession parses a work session written as start/end,
+// with an empty end while it's running
+func parseSession(v string) (Session, bool) {
+	start, end, ok := strings.Cut(v, "/")
+	if !ok {
+		return Session{}, false
+	}
+
+	var s Session
+	var err error
+	if s.Start, err = time.ParseInLocation(txtTime, start, time.Local); err != nil {
+		return Session{}, false
+	}
+	if end != "" {
+		if s.End, err = time.ParseInLocation(txtTime, end, time.Local); err != nil {
+			return Session{}, false
+		}
+	}
+
+	return s, true
+}
+
+// cutPriority parses a leading (A) priority from s and
+// returns it along with the rest of the string
+func cutPriority(s string) (string, string, bool) {
+	if len(s) < 4 || s[0] != '(' || s[2] != ')' || s[3] != ' ' ||
+		!isPriority(s[1:2]) {
+		return "", s, false
+	}
+
+	return s[1:2], s[4:], true
+}
+
+// isPriority reports whether p is a valid priority: A to Z
+func isPriority(p string) bool {
+	return len(p) == 1 && p[0] >= 'A' && p[0] <= 'Z'
+}
+
+// cutDate parses a leading todo.txt date from s and
+// returns it along with the rest of the string
+func cutDate(s string) (time.Time, string, bool) {
+	field, rest, _ := strings.Cut(s, " ")
+
+	d, err := time.ParseInLocation(txtDate, field, time.Local)
+	if err != nil {
+		return time.Time{}, s, false
+	}
+
+	return d, rest, true
+}
@@ -0,0 +1,208 @@
+package todo_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestTxtRoundTrip tests that importing and exporting todo.txt lines
+// preserves their contents
+func TestTxtRoundTrip(t *testing.T) {
+	input := strings.Join([]string{
+		"(A) 2024-01-01 Call mom +family @phone due:2024-02-01",
+		"x 2024-01-03 2024-01-01 Pay bills +home pri:B",
+		"x 2024-01-03 2024-01-02 Done without priority",
+		"2024-01-05 Read https://example.com later",
+		"Task without dates",
+	}, "\n") + "\n"
+
+	l, err := todo.ImportTxt(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 5 {
+		t.Fatalf("Expected %d items, got %d instead.", 5, len(l))
+	}
+
+	if l[0].Priority != "A" || l[0].Done {
+		t.Errorf("Expected pending priority A item, got %v instead.", l[0])
+	}
+
+	if l[1].Priority != "B" || !l[1].Done || l[1].Task != "Pay bills +home" {
+		t.Errorf("Expected completed priority B item, got %v instead.", l[1])
+	}
+
+	if l[1].CompletedAt.Format("2006-01-02") != "2024-01-03" ||
+		l[1].CreatedAt.Format("2006-01-02") != "2024-01-01" {
+		t.Errorf("Unexpected dates for %v", l[1])
+	}
+
+	var out bytes.Buffer
+	if err := l.ExportTxt(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != input {
+		t.Errorf("Expected %q, got %q instead.", input, out.String())
+	}
+}
+
+// TestTxtTags tests extracting projects, contexts and extras
+func TestTxtTags(t *testing.T) {
+	l := todo.List{}
+	l.Add("Call mom +family @phone due:2024-02-01 see https://example.com")
+
+	if exp := []string{"+family"}; !reflect.DeepEqual(l[0].Projects(), exp) {
+		t.Errorf("Expected %v, got %v instead.", exp, l[0].Projects())
+	}
+
+	if exp := []string{"@phone"}; !reflect.DeepEqual(l[0].Contexts(), exp) {
+		t.Errorf("Expected %v, got %v instead.", exp, l[0].Contexts())
+	}
+
+	exp := map[string]string{"due": "2024-02-01"}
+	if !reflect.DeepEqual(l[0].Extras(), exp) {
+		t.Errorf("Expected %v, got %v instead.", exp, l[0].Extras())
+	}
+
+	l.Add("Plan trip +family +travel")
+	l.AddAt([]int{2}, "Book hotel @phone +travel")
+
+	tags := []string{"+family", "+travel", "@phone"}
+	if !reflect.DeepEqual(l.Tags(), tags) {
+		t.Errorf("Expected %v, got %v instead.", tags, l.Tags())
+	}
+}
+
+// TestTxtMisread tests descriptions that look like a completion
+// marker, a priority or a date are read back as they were
+func TestTxtMisread(t *testing.T) {
+	l := todo.List{}
+	for _, task := range []string{"x marks the spot", "(B) movie night", "2024-05-01 meeting notes"} {
+		l.Add(task)
+	}
+	l.Add("2024-06-01 deadline")
+	l.Complete(4)
+	for k := range l {
+		l[k].CreatedAt = time.Time{}
+	}
+
+	var out bytes.Buffer
+	if err := l.ExportTxt(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	l2, err := todo.ImportTxt(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l2) != len(l) {
+		t.Fatalf("Expected %d items, got %d instead.", len(l), len(l2))
+	}
+	for k := range l {
+		if l2[k].Task != l[k].Task || l2[k].Done != l[k].Done || l2[k].Priority != "" {
+			t.Errorf("Expected %q, got %+v instead.", l[k].Task, l2[k])
+		}
+	}
+
+	if d := l2[3].CompletedAt.Format("2006-01-02"); d != l[3].CompletedAt.Format("2006-01-02") {
+		t.Errorf("Expected completion date kept, got %s instead.", d)
+	}
+}
+
+// TestTxtIDs tests items read from todo.txt get the same IDs
+// every time, even when their lines are identical
+func TestTxtIDs(t *testing.T) {
+	tf := filepath.Join(t.TempDir(), "todo.txt")
+	input := "2024-01-01 Call mom\nWater plants\nWater plants\n"
+	if err := os.WriteFile(tf, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &todo.TxtStore{Filename: tf}
+	var l1, l2, l3 todo.List
+	for _, l := range []*todo.List{&l1, &l2} {
+		if err := s.Load(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if l1[1].ID == l1[2].ID {
+		t.Errorf("Expected identical lines to get different IDs, got %q", l1[1].ID)
+	}
+
+	// Only the IDs that can't be derived from the line are written
+	l1.Edit(1, "Call mom and dad")
+	if err := s.Save(&l1); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "id:"); n != 2 {
+		t.Errorf("Expected %d IDs written, got %q instead.", 2, data)
+	}
+
+	if err := s.Load(&l3); err != nil {
+		t.Fatal(err)
+	}
+	for k := range l1 {
+		if l2[k].ID != l1[k].ID || l3[k].ID != l1[k].ID {
+			t.Errorf("Expected ID %q, got %q and %q instead.", l1[k].ID, l2[k].ID, l3[k].ID)
+		}
+	}
+}
+
+// TestTxtStoreAttributes tests a todo.txt store keeps the
+// attributes only this tool uses
+func TestTxtStoreAttributes(t *testing.T) {
+	s := &todo.TxtStore{Filename: filepath.Join(t.TempDir(), "todo.txt")}
+	start := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	remind := time.Date(2024, 5, 2, 8, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("Write report")
+	l.Add("Send report", todo.WithReminder(remind), todo.WithNotes("To: boss@example.com\nCC: team"))
+	if err := l.DependAt([]int{2}, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	l.StartAt([]int{1}, start)
+	l.StopAt([]int{1}, start.Add(90*time.Minute))
+	l.StartAt([]int{1}, start.Add(2*time.Hour))
+
+	if err := s.Save(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	l2 := todo.List{}
+	if err := s.Load(&l2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l2) != 2 || l2[0].Task != "Write report" || l2[1].Task != "Send report" {
+		t.Fatalf("Unexpected tasks %v", l2)
+	}
+
+	if !reflect.DeepEqual(l2[1].Deps, []string{l[0].ID}) || l2[0].ID != l[0].ID {
+		t.Errorf("Expected dependency on %q, got %v instead.", l[0].ID, l2[1].Deps)
+	}
+
+	if !l2[1].Remind.Equal(remind) || l2[1].Notes != l[1].Notes {
+		t.Errorf("Expected reminder and notes kept, got %v and %q instead.", l2[1].Remind, l2[1].Notes)
+	}
+
+	now := start.Add(3 * time.Hour)
+	if !l2[0].Running() || l2[0].Spent(now) != l[0].Spent(now) {
+		t.Errorf("Expected running sessions kept, got %v instead.", l2[0].Sessions)
+	}
+}
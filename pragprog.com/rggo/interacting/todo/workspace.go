@@ -0,0 +1,87 @@
+package todo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GlobalList is the name of the user's list, used when no list is
+// found in the current directory or its parents
+const GlobalList = "default"
+
+// DataDir returns the directory holding the named lists, following
+// the XDG conventions: $XDG_DATA_HOME/todo, or ~/.local/share/todo
+// when XDG_DATA_HOME is not set
+func DataDir() (string, error) {
+	if d := os.Getenv("XDG_DATA_HOME"); d != "" {
+		return filepath.Join(d, "todo"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", "todo"), nil
+}
+
+// ListFile returns the file name of the named list, creating the
+// data directory if it doesn't exist yet
+func ListFile(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("Invalid list name %q", name)
+	}
+
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// Lists returns the names of the lists in the data directory, sorted
+func Lists() ([]string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(f), ".json"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// FindList searches dir and its parents, up to the filesystem root,
+// for a file with the given name and returns the nearest one.
+// It reports false when no directory holds the file
+func FindList(dir, name string) (string, bool) {
+	for {
+		f := filepath.Join(dir, name)
+		if fi, err := os.Stat(f); err == nil && !fi.IsDir() {
+			return f, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
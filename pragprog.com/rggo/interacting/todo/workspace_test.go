@@ -0,0 +1,108 @@
+package todo_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pragprog.com/rggo/interacting/todo"
+)
+
+// TestListFile tests the files of named lists are kept in the data directory
+func TestListFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+
+	f, err := todo.ListFile("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := filepath.Join(dir, "todo", "work.json")
+	if f != exp {
+		t.Errorf("Expected %q, got %q instead.", exp, f)
+	}
+
+	if _, err := os.Stat(filepath.Dir(exp)); err != nil {
+		t.Errorf("Expected data directory to be created: %s", err)
+	}
+
+	for _, name := range []string{"", ".hidden", "../work", `a\b`} {
+		if _, err := todo.ListFile(name); err == nil {
+			t.Errorf("Expected error for list name %q", name)
+		}
+	}
+}
+
+// TestLists tests the names of the lists in the data directory
+func TestLists(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	names, err := todo.Lists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("Expected no lists, got %v instead.", names)
+	}
+
+	for _, name := range []string{"work", "oncall", "personal"} {
+		f, err := todo.ListFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l := todo.List{}
+		l.Add("Task in " + name)
+		if err := l.Save(f); err != nil {
+			t.Fatal(err)
+		}
+
+		// Files kept next to the list are not lists themselves
+		a := todo.List{}
+		if err := a.Save(todo.ArchiveFile(f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err = todo.Lists()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"oncall", "personal", "work"}
+	if !reflect.DeepEqual(names, exp) {
+		t.Errorf("Expected %v, got %v instead.", exp, names)
+	}
+}
+
+// TestFindList tests the nearest list is found in the parent directories
+func TestFindList(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b", "c")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, ok := todo.FindList(dir, ".todo.json"); ok {
+		t.Fatalf("Expected no list, found %q instead.", f)
+	}
+
+	for _, d := range []string{root, filepath.Join(root, "a")} {
+		if err := os.WriteFile(filepath.Join(d, ".todo.json"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A directory named like the list is not a list
+	if err := os.Mkdir(filepath.Join(root, "a", "b", ".todo.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := todo.FindList(dir, ".todo.json")
+	exp := filepath.Join(root, "a", ".todo.json")
+	if !ok || f != exp {
+		t.Errorf("Expected %q, got %q instead.", exp, f)
+	}
+}